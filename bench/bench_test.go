@@ -8,6 +8,9 @@ import (
 	"bench/impl/libmacaroon"
 	"bench/impl/mack"
 	"bench/testvector"
+
+	mackpkg "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/sensible"
 )
 
 func createImplementations(tb testing.TB) []impl.Implementation {
@@ -189,3 +192,42 @@ func BenchmarkDecodeFromV2(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkBuilder(b *testing.B) {
+	const caveats = 8
+	args := testvector.RandomMacaroonSpec()
+	im := &mack.Implementation{}
+	mi, err := im.NewMacaroon(args)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m := mi.Macaroon.(*mackpkg.Macaroon)
+	sch := sensible.Scheme()
+	cids := make([][]byte, caveats)
+	for i := range cids {
+		cids[i] = []byte(base64.StdEncoding.EncodeToString(testvector.RandomBytes(100)))
+	}
+	b.Run("api=add", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			am := *m
+			for _, cid := range cids {
+				if am, err = sch.AddFirstPartyCaveat(&am, cid); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("api=builder", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			bld := sch.NewBuilder(m)
+			for _, cid := range cids {
+				bld.FirstParty(cid)
+			}
+			if _, err = bld.Build(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
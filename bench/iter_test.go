@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"testing"
+
+	"bench/impl/mack"
+	"bench/testvector"
+
+	mackpkg "github.com/justenwalker/mack"
+)
+
+func createLargeStack(tb testing.TB) mackpkg.Stack {
+	tb.Helper()
+	im := &mack.Implementation{}
+	ms, err := im.NewMacaroons(testvector.LargeMacaroon())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return *ms.Slice.(*mackpkg.Stack)
+}
+
+func BenchmarkCaveats(b *testing.B) {
+	st := createLargeStack(b)
+	m := st.Target()
+	var n int
+	b.Run("api=slice", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, c := range m.FirstPartyCaveats() {
+				n += len(c.ID())
+			}
+		}
+	})
+	b.Run("api=iter", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, c := range m.FirstParty() {
+				n += len(c.ID())
+			}
+		}
+	})
+	_ = n
+}
+
+func BenchmarkPredicates(b *testing.B) {
+	v := mackpkg.InsecureVerifiedStack(createLargeStack(b))
+	var n int
+	b.Run("api=slice", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, p := range v.Predicates() {
+				n += len(p.CaveatID)
+			}
+		}
+	})
+	b.Run("api=iter", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for p := range v.AllPredicates() {
+				n += len(p.CaveatID)
+			}
+		}
+	})
+	_ = n
+}
@@ -0,0 +1,117 @@
+package mack
+
+import (
+	"fmt"
+)
+
+// Builder accumulates first-party and third-party caveats to append to a macaroon.
+// Unlike repeated calls to [Scheme.AddFirstPartyCaveat] and [Scheme.AddThirdPartyCaveat], which copy the whole
+// macaroon for every caveat, [Builder.Build] creates the attenuated macaroon in a single allocation.
+// Creating the Builder is one more allocation, which also holds the first few caveats.
+//
+// The methods adding caveats may be chained. The first invalid caveat is recorded, and later caveats are ignored;
+// the error is returned by [Builder.Build]. The caveat slices are retained until the macaroon is built,
+// so they must not be modified before then. A Builder is not safe for concurrent use.
+type Builder struct {
+	s       *Scheme
+	m       *Macaroon
+	caveats []builderCaveat
+	err     error
+
+	// buf holds the first caveats, so building a macaroon with a few caveats does not grow the caveats slice.
+	buf [builderCaveats]builderCaveat
+}
+
+// builderCaveats is the number of caveats a [Builder] holds before it allocates.
+const builderCaveats = 8
+
+// builderCaveat is a caveat accumulated by a [Builder].
+// If key is set, the caveat is a third-party caveat, and its VID is created from the key when it is built.
+type builderCaveat struct {
+	RawCaveat
+	key []byte
+}
+
+func (c *builderCaveat) vidSize(s *Scheme) int {
+	if c.key == nil {
+		return len(c.VID)
+	}
+	return len(c.key) + s.overhead
+}
+
+// NewBuilder creates a [Builder] which appends caveats to the macaroon.
+func (s *Scheme) NewBuilder(m *Macaroon) *Builder {
+	b := &Builder{s: s, m: m}
+	if m.IsZero() {
+		b.err = fmt.Errorf("%w: Builder: empty macaroon", ErrInvalidArgument)
+	}
+	return b
+}
+
+// add appends the caveat to the builder, starting with the embedded buffer.
+func (b *Builder) add(c builderCaveat) {
+	if b.caveats == nil {
+		b.caveats = b.buf[:0]
+	}
+	b.caveats = append(b.caveats, c)
+}
+
+// FirstParty adds a first-party caveat with the predicate.
+func (b *Builder) FirstParty(predicate []byte) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(predicate) == 0 {
+		b.err = fmt.Errorf("%w: Builder.FirstParty: caveat %d: empty predicate", ErrInvalidArgument, len(b.caveats))
+		return b
+	}
+	b.add(builderCaveat{RawCaveat: RawCaveat{CID: predicate}})
+	return b
+}
+
+// ThirdParty adds a third-party caveat, like [Scheme.AddThirdPartyCaveat].
+// Coordinating the link between the cKey and cID is out of scope for this function.
+func (b *Builder) ThirdParty(cKey []byte, cID []byte, location string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	switch {
+	case len(cKey) == 0:
+		b.err = fmt.Errorf("%w: Builder.ThirdParty: caveat %d: empty key (cK)", ErrInvalidArgument, len(b.caveats))
+		return b
+	case len(cID) == 0:
+		b.err = fmt.Errorf("%w: Builder.ThirdParty: caveat %d: empty caveat id (cID)", ErrInvalidArgument, len(b.caveats))
+		return b
+	}
+	b.add(builderCaveat{
+		RawCaveat: RawCaveat{CID: cID, Location: location},
+		key:       cKey,
+	})
+	return b
+}
+
+// Len returns the number of caveats added to the builder.
+func (b *Builder) Len() int {
+	return len(b.caveats)
+}
+
+// Err returns the error recorded for the first invalid caveat, if any.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// Build creates a new macaroon with the caveats appended, or returns the first error.
+// If no caveats were added, it returns the macaroon unchanged.
+func (b *Builder) Build() (Macaroon, error) {
+	if b.err != nil {
+		return Macaroon{}, b.err
+	}
+	if len(b.caveats) == 0 {
+		return *b.m, nil
+	}
+	nmd, err := b.m.data.appendBuilt(b.s, b.caveats)
+	if err != nil {
+		return Macaroon{}, err
+	}
+	return fromData(nmd), nil
+}
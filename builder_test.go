@@ -0,0 +1,106 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("org = acme"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	const seed = 1001
+	testhelpers.SeedRandom(seed)
+	built, err := sch.NewBuilder(&m).
+		FirstParty([]byte("a > 1")).
+		ThirdParty(testhelpers.ThirdPartyKey, []byte("3p"), "https://auth.example.org").
+		FirstParty([]byte("b > 2")).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	// adding the caveats one at a time, with the same random source, should create an identical macaroon.
+	testhelpers.SeedRandom(seed)
+	expected, err := sch.AddFirstPartyCaveat(&m, []byte("a > 1"))
+	if err != nil {
+		t.Fatalf("AddFirstPartyCaveat: %v", err)
+	}
+	if expected, err = sch.AddThirdPartyCaveat(&expected, testhelpers.ThirdPartyKey, []byte("3p"), "https://auth.example.org"); err != nil {
+		t.Fatalf("AddThirdPartyCaveat: %v", err)
+	}
+	if expected, err = sch.AddFirstPartyCaveat(&expected, []byte("b > 2")); err != nil {
+		t.Fatalf("AddFirstPartyCaveat: %v", err)
+	}
+	if !built.Equal(&expected) {
+		t.Fatalf("Build: macaroons do not match:\nwant %v\ngot  %v", &expected, &built)
+	}
+	if len(built.Caveats()) != 4 {
+		t.Fatalf("Build: expected 4 caveats, got %d", len(built.Caveats()))
+	}
+	discharge, err := sch.NewMacaroon("https://auth.example.org", []byte("3p"), testhelpers.ThirdPartyKey, []byte("user = alice"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	stack, err := sch.PrepareStack(&built, []mack.Macaroon{discharge})
+	if err != nil {
+		t.Fatalf("PrepareStack: %v", err)
+	}
+	if _, err = sch.Verify(context.Background(), testhelpers.RootKey, stack); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if same, err := sch.NewBuilder(&m).Build(); err != nil || !same.Equal(&m) {
+		t.Fatalf("Build: expected an empty builder to return the macaroon unchanged: %v", err)
+	}
+}
+
+func TestBuilder_Build_invalid(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("org = acme"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	tests := []struct {
+		name    string
+		builder *mack.Builder
+	}{
+		{name: "zero-macaroon", builder: sch.NewBuilder(&mack.Macaroon{}).FirstParty([]byte("a > 1"))},
+		{name: "empty-predicate", builder: sch.NewBuilder(&m).FirstParty([]byte("a > 1")).FirstParty(nil)},
+		{name: "empty-key", builder: sch.NewBuilder(&m).ThirdParty(nil, []byte("3p"), "loc")},
+		{name: "empty-caveat-id", builder: sch.NewBuilder(&m).ThirdParty(testhelpers.ThirdPartyKey, nil, "loc")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.builder.FirstParty([]byte("b > 2"))
+			if !errors.Is(tt.builder.Err(), mack.ErrInvalidArgument) {
+				t.Fatalf("Err: expected ErrInvalidArgument, got %v", tt.builder.Err())
+			}
+			if _, err := tt.builder.Build(); !errors.Is(err, mack.ErrInvalidArgument) {
+				t.Fatalf("Build: expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBuilder_Build_tooLarge(t *testing.T) {
+	if mack.MaxFieldSize > 1<<20 {
+		t.Skip("MaxFieldSize is too large to test")
+	}
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("org = acme"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	large := make([]byte, mack.MaxFieldSize+1)
+	if _, err = sch.NewBuilder(&m).FirstParty([]byte("a > 1")).FirstParty(large).Build(); !errors.Is(err, mack.ErrInvalidArgument) {
+		t.Fatalf("Build: expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err = sch.NewBuilder(&m).ThirdParty(large, []byte("3p"), "loc").Build(); !errors.Is(err, mack.ErrInvalidArgument) {
+		t.Fatalf("Build: expected ErrInvalidArgument, got %v", err)
+	}
+}
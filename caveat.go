@@ -1,9 +1,5 @@
 package mack
 
-import (
-	"unsafe"
-)
-
 type Caveat struct {
 	*caveatData
 }
@@ -24,5 +20,5 @@ func (c *Caveat) data() []byte {
 	if c.caveatData.vidSize == 0 {
 		return c.caveatData.cid()
 	}
-	return unsafe.Slice(&c.caveatData.data[0], c.caveatData.vidSize+c.caveatData.idSize)
+	return c.caveatData.hmacData()
 }
@@ -0,0 +1,251 @@
+// Package caveat defines a small, interoperable language for first-party caveats.
+//
+// The macaroon spec leaves the contents of a caveat opaque, so every service tends to invent its own encoding.
+// This package gives services a shared dialect: a caveat is a single expression of the form
+//
+//	key op value
+//
+// where the supported operators are:
+//
+//	=        equal
+//	!=       not equal
+//	<        less than
+//	<=       less than or equal
+//	>        greater than
+//	>=       greater than or equal
+//	in       member of a list:     org in (org1, org2)
+//	not in   not member of a list: app not in (sensitive_app)
+//	matches  glob match (see [path.Match]): request.path matches "/user/*"
+//	=~       regular expression match, anchored at both ends: request.path =~ "/user/.*"
+//
+// Values are either bare tokens or double-quoted strings using Go string escapes.
+// The operand of an ordering operator (<, <=, >, >=) which is a bare token that parses as a number
+// or an RFC3339 timestamp is typed accordingly, and compared with the attribute as that type.
+// The other operators always compare the attribute with the text of the value, so `acct = 00123`
+// does not match the attribute "123".
+//
+// Expressions are constructed with the helper functions ([Eq], [In], [Lt], ...) or by [Parse],
+// encoded with [Expr.Bytes] for [mack.Scheme.AddFirstPartyCaveat], and evaluated with an [Evaluator]
+// which implements [mack.PredicateChecker].
+package caveat
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Op is a caveat operator.
+type Op int
+
+const (
+	OpUnknown      = Op(iota) // ?
+	OpEqual                   // =
+	OpNotEqual                // !=
+	OpLess                    // <
+	OpLessEqual               // <=
+	OpGreater                 // >
+	OpGreaterEqual            // >=
+	OpIn                      // in
+	OpNotIn                   // not in
+	OpGlob                    // matches
+	OpRegex                   // =~
+)
+
+// String returns the textual representation of the operator as it appears in a caveat.
+func (op Op) String() string {
+	switch op {
+	case OpEqual:
+		return "="
+	case OpNotEqual:
+		return "!="
+	case OpLess:
+		return "<"
+	case OpLessEqual:
+		return "<="
+	case OpGreater:
+		return ">"
+	case OpGreaterEqual:
+		return ">="
+	case OpIn:
+		return "in"
+	case OpNotIn:
+		return "not in"
+	case OpGlob:
+		return "matches"
+	case OpRegex:
+		return "=~"
+	default:
+		return "?"
+	}
+}
+
+// list returns true if the operator takes a list of values.
+func (op Op) list() bool {
+	return op == OpIn || op == OpNotIn
+}
+
+// ordered returns true if the operator compares the order of values, rather than their text.
+func (op Op) ordered() bool {
+	return op == OpLess || op == OpLessEqual || op == OpGreater || op == OpGreaterEqual
+}
+
+// Kind is the type of Value.
+type Kind int
+
+const (
+	KindString = Kind(iota)
+	KindNumber
+	KindTime
+)
+
+// Value is a typed caveat operand.
+type Value struct {
+	Kind Kind
+	Str  string
+	Num  float64
+	Time time.Time
+}
+
+// String creates a string Value.
+func String(s string) Value {
+	return Value{Kind: KindString, Str: s}
+}
+
+// Number creates a numeric Value.
+// It panics if n is NaN or infinite, since those can not be encoded as a number, and compare with nothing.
+func Number(n float64) Value {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		panic(fmt.Sprintf("caveat.Number: %v is not a finite number", n))
+	}
+	return Value{Kind: KindNumber, Num: n}
+}
+
+// Time creates a timestamp Value.
+func Time(t time.Time) Value {
+	return Value{Kind: KindTime, Time: t}
+}
+
+// String returns the encoded form of the value.
+// Strings are quoted if they would otherwise be ambiguous.
+func (v Value) String() string {
+	switch v.Kind {
+	case KindNumber:
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case KindTime:
+		return v.Time.Format(time.RFC3339Nano)
+	default:
+		if needsQuote(v.Str) {
+			return strconv.Quote(v.Str)
+		}
+		return v.Str
+	}
+}
+
+// Expr is a parsed caveat expression.
+type Expr struct {
+	Key    string
+	Op     Op
+	Values []Value
+
+	// re caches the compiled regular expression for OpRegex.
+	re *regexp.Regexp
+}
+
+// String returns the canonical text encoding of the expression.
+func (e Expr) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Key)
+	sb.WriteByte(' ')
+	sb.WriteString(e.Op.String())
+	sb.WriteByte(' ')
+	if e.Op.list() {
+		sb.WriteByte('(')
+		for i, v := range e.Values {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(v.String())
+		}
+		sb.WriteByte(')')
+		return sb.String()
+	}
+	if len(e.Values) > 0 {
+		sb.WriteString(e.Values[0].String())
+	}
+	return sb.String()
+}
+
+// Bytes returns the encoded caveat, suitable for [mack.Scheme.AddFirstPartyCaveat].
+func (e Expr) Bytes() []byte {
+	return []byte(e.String())
+}
+
+// Eq creates a caveat asserting that the attribute key is equal to value.
+func Eq(key string, value Value) Expr {
+	return Expr{Key: key, Op: OpEqual, Values: []Value{value}}
+}
+
+// NotEq creates a caveat asserting that the attribute key is not equal to value.
+func NotEq(key string, value Value) Expr {
+	return Expr{Key: key, Op: OpNotEqual, Values: []Value{value}}
+}
+
+// Lt creates a caveat asserting that the attribute key is less than value.
+func Lt(key string, value Value) Expr {
+	return Expr{Key: key, Op: OpLess, Values: []Value{value}}
+}
+
+// Le creates a caveat asserting that the attribute key is less than or equal to value.
+func Le(key string, value Value) Expr {
+	return Expr{Key: key, Op: OpLessEqual, Values: []Value{value}}
+}
+
+// Gt creates a caveat asserting that the attribute key is greater than value.
+func Gt(key string, value Value) Expr {
+	return Expr{Key: key, Op: OpGreater, Values: []Value{value}}
+}
+
+// Ge creates a caveat asserting that the attribute key is greater than or equal to value.
+func Ge(key string, value Value) Expr {
+	return Expr{Key: key, Op: OpGreaterEqual, Values: []Value{value}}
+}
+
+// In creates a caveat asserting that the attribute key is equal to one of the values.
+func In(key string, values ...Value) Expr {
+	return Expr{Key: key, Op: OpIn, Values: values}
+}
+
+// NotIn creates a caveat asserting that the attribute key is not equal to any of the values.
+func NotIn(key string, values ...Value) Expr {
+	return Expr{Key: key, Op: OpNotIn, Values: values}
+}
+
+// Glob creates a caveat asserting that the attribute key matches the glob pattern.
+// The pattern syntax is that of [path.Match].
+func Glob(key string, pattern string) Expr {
+	return Expr{Key: key, Op: OpGlob, Values: []Value{String(pattern)}}
+}
+
+// Regex creates a caveat asserting that the attribute key matches the regular expression.
+// The expression is anchored, so it must match the entire attribute value.
+func Regex(key string, pattern string) Expr {
+	return Expr{Key: key, Op: OpRegex, Values: []Value{String(pattern)}}
+}
+
+// needsQuote returns true if the string cannot be written as a bare token
+// without changing its meaning when parsed again.
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if !isBareByte(s[i]) {
+			return true
+		}
+	}
+	return parseBare(s).Kind != KindString
+}
@@ -0,0 +1,138 @@
+package caveat
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justenwalker/mack"
+)
+
+// Attributes provides the values that caveat expressions are evaluated against.
+type Attributes interface {
+	// Attribute returns the value of the attribute named by key, and false if it is not defined.
+	// An error indicates the attribute could not be resolved at this time.
+	Attribute(ctx context.Context, key string) (string, bool, error)
+}
+
+// Map is a static set of Attributes.
+type Map map[string]string
+
+// Attribute returns the value of the attribute named by key.
+func (m Map) Attribute(_ context.Context, key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+// Evaluator evaluates caveat expressions against a set of Attributes.
+// It implements [mack.PredicateChecker], so it can be used to clear a [mack.VerifiedStack].
+//
+// Caveats fail closed: if the attribute is not defined, or the attribute cannot be compared
+// with the operand (ie: a non-numeric attribute compared with a number), the predicate is not satisfied.
+type Evaluator struct {
+	Attributes Attributes
+}
+
+// CheckPredicate parses the predicate and evaluates it.
+// A predicate which is not a valid expression returns an error wrapping [ErrSyntax].
+func (ev *Evaluator) CheckPredicate(ctx context.Context, predicate []byte) (bool, error) {
+	e, err := Parse(predicate)
+	if err != nil {
+		return false, fmt.Errorf("caveat.Evaluator: %w", err)
+	}
+	return ev.Eval(ctx, e)
+}
+
+// Eval evaluates the expression.
+func (ev *Evaluator) Eval(ctx context.Context, e Expr) (bool, error) {
+	if err := e.compile(); err != nil {
+		return false, fmt.Errorf("caveat.Evaluator: %w", err)
+	}
+	attr, ok, err := ev.Attributes.Attribute(ctx, e.Key)
+	if err != nil {
+		return false, fmt.Errorf("caveat.Evaluator: failed to resolve attribute '%s': %w", e.Key, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	switch e.Op {
+	case OpEqual:
+		return attr == e.Values[0].pattern(), nil
+	case OpNotEqual:
+		return attr != e.Values[0].pattern(), nil
+	case OpLess:
+		c, cok := compare(attr, e.Values[0])
+		return cok && c < 0, nil
+	case OpLessEqual:
+		c, cok := compare(attr, e.Values[0])
+		return cok && c <= 0, nil
+	case OpGreater:
+		c, cok := compare(attr, e.Values[0])
+		return cok && c > 0, nil
+	case OpGreaterEqual:
+		c, cok := compare(attr, e.Values[0])
+		return cok && c >= 0, nil
+	case OpIn:
+		return contains(attr, e.Values), nil
+	case OpNotIn:
+		return !contains(attr, e.Values), nil
+	case OpGlob:
+		matched, merr := path.Match(e.Values[0].pattern(), attr)
+		if merr != nil {
+			return false, fmt.Errorf("caveat.Evaluator: %w", merr)
+		}
+		return matched, nil
+	case OpRegex:
+		return e.re.MatchString(attr), nil
+	default:
+		return false, fmt.Errorf("caveat.Evaluator: unknown operator %d", int(e.Op))
+	}
+}
+
+// contains returns true if the attribute is equal to the text of one of the values.
+func contains(attr string, values []Value) bool {
+	for _, v := range values {
+		if attr == v.pattern() {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares the order of the attribute and the value, interpreting the attribute as the same Kind as the value.
+// It returns false if the attribute cannot be interpreted as that Kind, or either number is NaN or infinite.
+func compare(attr string, v Value) (int, bool) {
+	switch v.Kind {
+	case KindNumber:
+		n, err := strconv.ParseFloat(attr, 64)
+		if err != nil || !finite(n) || !finite(v.Num) {
+			return 0, false
+		}
+		switch {
+		case n < v.Num:
+			return -1, true
+		case n > v.Num:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case KindTime:
+		t, err := time.Parse(time.RFC3339Nano, attr)
+		if err != nil {
+			return 0, false
+		}
+		return t.Compare(v.Time), true
+	default:
+		return strings.Compare(attr, v.Str), true
+	}
+}
+
+func finite(n float64) bool {
+	return !math.IsNaN(n) && !math.IsInf(n, 0)
+}
+
+var _ mack.PredicateChecker = (*Evaluator)(nil)
@@ -0,0 +1,153 @@
+package caveat_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/caveat"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestEvaluator_CheckPredicate(t *testing.T) {
+	ev := caveat.Evaluator{
+		Attributes: caveat.Map{
+			"org":          "acme",
+			"count":        "5",
+			"time":         "2006-01-02T15:04:05Z",
+			"request.path": "/user/foo",
+		},
+	}
+	tests := []struct {
+		predicate string
+		expected  bool
+	}{
+		{`org = acme`, true},
+		{`org = other`, false},
+		{`org != other`, true},
+		{`org != acme`, false},
+		{`count < 10`, true},
+		{`count < 5`, false},
+		{`count <= 5`, true},
+		{`count > 4.5`, true},
+		{`count >= 6`, false},
+		{`org < b`, true},
+		{`org > b`, false},
+		{`time < 2007-01-01T00:00:00Z`, true},
+		{`time > 2007-01-01T00:00:00Z`, false},
+		{`org in (foo, acme)`, true},
+		{`org in (foo, bar)`, false},
+		{`org not in (foo, bar)`, true},
+		{`org not in (foo, acme)`, false},
+		{`request.path matches /user/*`, true},
+		{`request.path matches /admin/*`, false},
+		{`request.path =~ "/user/.*"`, true},
+		{`request.path =~ "/user"`, false},
+		{`org < 10`, false},
+		{`missing = acme`, false},
+		{`missing != acme`, false},
+		{`missing not in (acme)`, false},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.predicate, func(t *testing.T) {
+			ok, err := ev.CheckPredicate(ctx, []byte(tt.predicate))
+			if err != nil {
+				t.Fatalf("CheckPredicate(%q): unexpected error: %v", tt.predicate, err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("CheckPredicate(%q): want %v, got %v", tt.predicate, tt.expected, ok)
+			}
+		})
+	}
+	t.Run("syntax-error", func(t *testing.T) {
+		_, err := ev.CheckPredicate(ctx, []byte(`org ?`))
+		if !errors.Is(err, caveat.ErrSyntax) {
+			t.Fatalf("expected ErrSyntax, got %v", err)
+		}
+	})
+}
+
+func TestEvaluator_CheckPredicate_numbers(t *testing.T) {
+	tests := []struct {
+		attr      string
+		predicate string
+		expected  bool
+	}{
+		{"NaN", `amount <= 100`, false},
+		{"NaN", `amount >= 1000000`, false},
+		{"NaN", `amount = 5`, false},
+		{"NaN", `amount != 5`, true},
+		{"Inf", `amount > 100`, false},
+		{"-Inf", `amount < 100`, false},
+		{"1e999", `amount > 100`, false},
+		{"50", `amount < 1e999`, false},
+		{"123", `amount = 00123`, false},
+		{"00123", `amount = 00123`, true},
+		{"1e2", `amount = 100`, false},
+		{"100", `amount = 100`, true},
+		{"123", `amount != 00123`, true},
+		{"123", `amount in (000123.0)`, false},
+		{"123", `amount not in (000123.0)`, true},
+		{"2006-01-02T15:04:05+00:00", `amount = 2006-01-02T15:04:05Z`, false},
+		{"1e2", `amount >= 100`, true},
+		{"00123", `amount < 124`, true},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.attr+" "+tt.predicate, func(t *testing.T) {
+			ev := caveat.Evaluator{Attributes: caveat.Map{"amount": tt.attr}}
+			ok, err := ev.CheckPredicate(ctx, []byte(tt.predicate))
+			if err != nil {
+				t.Fatalf("CheckPredicate(%q): unexpected error: %v", tt.predicate, err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("CheckPredicate(%q) with amount=%q: want %v, got %v", tt.predicate, tt.attr, tt.expected, ok)
+			}
+		})
+	}
+	t.Run("constructed", func(t *testing.T) {
+		ev := caveat.Evaluator{Attributes: caveat.Map{"amount": "100"}}
+		for _, e := range []caveat.Expr{
+			caveat.Lt("amount", caveat.Value{Kind: caveat.KindNumber, Num: math.Inf(1)}),
+			caveat.Ge("amount", caveat.Value{Kind: caveat.KindNumber, Num: math.NaN()}),
+			caveat.Le("amount", caveat.Value{Kind: caveat.KindNumber, Num: math.NaN()}),
+		} {
+			ok, err := ev.Eval(ctx, e)
+			if err != nil {
+				t.Fatalf("Eval(%q): unexpected error: %v", e, err)
+			}
+			if ok {
+				t.Fatalf("Eval(%q): expected false", e)
+			}
+		}
+		ok, err := ev.Eval(ctx, caveat.Eq("amount", caveat.Number(100)))
+		if err != nil || !ok {
+			t.Fatalf("Eval(amount = 100): want true, got %v, %v", ok, err)
+		}
+	})
+}
+
+func TestEvaluator_clear(t *testing.T) {
+	ctx := context.Background()
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte(`id`), testhelpers.RootKey,
+		caveat.Eq("org", caveat.String("acme")).Bytes(),
+		caveat.In("app", caveat.String("a"), caveat.String("b")).Bytes(),
+	)
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	vs, err := sch.Verify(ctx, testhelpers.RootKey, mack.Stack{m})
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err = vs.Clear(ctx, &caveat.Evaluator{Attributes: caveat.Map{"org": "acme", "app": "b"}}); err != nil {
+		t.Fatalf("Clear: unexpected error: %v", err)
+	}
+	if err = vs.Clear(ctx, &caveat.Evaluator{Attributes: caveat.Map{"org": "acme", "app": "c"}}); err == nil {
+		t.Fatalf("Clear: expected error")
+	}
+}
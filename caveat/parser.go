@@ -0,0 +1,277 @@
+package caveat
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type stringError string
+
+func (e stringError) Error() string {
+	return string(e)
+}
+
+// ErrSyntax is returned by [Parse] when the caveat is not a valid expression.
+const ErrSyntax = stringError("caveat: syntax error")
+
+// Parse parses a caveat expression.
+func Parse(bs []byte) (Expr, error) {
+	p := parser{src: string(bs)}
+	return p.parse()
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) parse() (Expr, error) {
+	var e Expr
+	var err error
+	p.skipSpace()
+	if e.Key, err = p.key(); err != nil {
+		return Expr{}, err
+	}
+	p.skipSpace()
+	if e.Op, err = p.op(); err != nil {
+		return Expr{}, err
+	}
+	p.skipSpace()
+	if e.Op.list() {
+		if e.Values, err = p.list(); err != nil {
+			return Expr{}, err
+		}
+	} else {
+		var v Value
+		if v, err = p.value(e.Op.ordered()); err != nil {
+			return Expr{}, err
+		}
+		e.Values = []Value{v}
+	}
+	p.skipSpace()
+	if p.pos != len(p.src) {
+		return Expr{}, p.errorf("unexpected trailing input %q", p.src[p.pos:])
+	}
+	if err = e.compile(); err != nil {
+		return Expr{}, err
+	}
+	return e, nil
+}
+
+func (p *parser) key() (string, error) {
+	start := p.pos
+	for p.pos < len(p.src) && isKeyByte(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected key")
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) op() (Op, error) {
+	rest := p.src[p.pos:]
+	// order matters: longer operators must be tested before their prefixes.
+	for _, op := range []Op{OpRegex, OpNotEqual, OpLessEqual, OpGreaterEqual, OpEqual, OpLess, OpGreater} {
+		if s := op.String(); strings.HasPrefix(rest, s) {
+			p.pos += len(s)
+			return op, nil
+		}
+	}
+	word := p.word()
+	switch word {
+	case "in":
+		return OpIn, nil
+	case "matches":
+		return OpGlob, nil
+	case "not":
+		p.skipSpace()
+		if p.word() == "in" {
+			return OpNotIn, nil
+		}
+		return OpUnknown, p.errorf("expected 'in' after 'not'")
+	case "":
+		return OpUnknown, p.errorf("expected operator")
+	default:
+		return OpUnknown, p.errorf("unknown operator %q", word)
+	}
+}
+
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) list() ([]Value, error) {
+	if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+		return nil, p.errorf("expected '('")
+	}
+	p.pos++
+	var values []Value
+	for {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ')' && len(values) == 0 {
+			p.pos++
+			return values, nil
+		}
+		v, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("expected ',' or ')'")
+		}
+		switch p.src[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return values, nil
+		default:
+			return nil, p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+// value parses a quoted string or a bare token. If typed is true, bare tokens are typed by [parseBare].
+func (p *parser) value(typed bool) (Value, error) {
+	if p.pos >= len(p.src) {
+		return Value{}, p.errorf("expected value")
+	}
+	if p.src[p.pos] == '"' {
+		return p.quoted()
+	}
+	start := p.pos
+	for p.pos < len(p.src) && isBareByte(p.src[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		return Value{}, p.errorf("expected value")
+	}
+	if !typed {
+		return String(p.src[start:p.pos]), nil
+	}
+	return parseBare(p.src[start:p.pos]), nil
+}
+
+func (p *parser) quoted() (Value, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			s, err := strconv.Unquote(p.src[start:p.pos])
+			if err != nil {
+				return Value{}, fmt.Errorf("%w: invalid quoted string at offset %d: %w", ErrSyntax, start, err)
+			}
+			return String(s), nil
+		}
+		p.pos++
+	}
+	return Value{}, p.errorf("unterminated quoted string")
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: offset %d: %s", ErrSyntax, p.pos, fmt.Sprintf(format, args...))
+}
+
+// compile validates the operands for the expression's operator, compiling regular expressions.
+func (e *Expr) compile() error {
+	if !e.Op.list() && len(e.Values) != 1 {
+		return fmt.Errorf("%w: operator '%s' requires exactly one value", ErrSyntax, e.Op)
+	}
+	switch e.Op { //nolint:exhaustive
+	case OpGlob:
+		if _, err := path.Match(e.Values[0].pattern(), ""); err != nil {
+			return fmt.Errorf("%w: invalid glob pattern %q: %w", ErrSyntax, e.Values[0].pattern(), err)
+		}
+	case OpRegex:
+		if e.re != nil {
+			return nil
+		}
+		re, err := regexp.Compile(`^(?:` + e.Values[0].pattern() + `)$`)
+		if err != nil {
+			return fmt.Errorf("%w: invalid regular expression %q: %w", ErrSyntax, e.Values[0].pattern(), err)
+		}
+		e.re = re
+	}
+	return nil
+}
+
+// pattern returns the text of the value, which the equality, list and pattern operators compare with the attribute.
+// Numbers and times use their encoded form.
+func (v Value) pattern() string {
+	if v.Kind == KindString {
+		return v.Str
+	}
+	return v.String()
+}
+
+// parseBare interprets an unquoted token, typing it as a number or time if possible.
+func parseBare(s string) Value {
+	if looksNumeric(s) {
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return Number(n)
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return Time(t)
+	}
+	return String(s)
+}
+
+// looksNumeric prevents tokens such as "Inf" or "NaN" from being interpreted as numbers.
+func looksNumeric(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return s != "" && isDigit(s[0])
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isKeyByte(b byte, first bool) bool {
+	if isLetter(b) || b == '_' {
+		return true
+	}
+	if first {
+		return false
+	}
+	return isDigit(b) || b == '.' || b == '-' || b == ':' || b == '/'
+}
+
+func isBareByte(b byte) bool {
+	if b <= ' ' || b >= 0x7f {
+		return false
+	}
+	switch b {
+	case '"', '(', ')', ',', '\\':
+		return false
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package caveat_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/justenwalker/mack/caveat"
+)
+
+func TestParse(t *testing.T) {
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		input    string
+		expected caveat.Expr
+		encoded  string
+	}{
+		{
+			input:    `org = acme`,
+			expected: caveat.Eq("org", caveat.String("acme")),
+		},
+		{
+			input:    `org="acme corp"`,
+			expected: caveat.Eq("org", caveat.String("acme corp")),
+			encoded:  `org = "acme corp"`,
+		},
+		{
+			input:    `user != root`,
+			expected: caveat.NotEq("user", caveat.String("root")),
+		},
+		{
+			input:    `count < 10`,
+			expected: caveat.Lt("count", caveat.Number(10)),
+		},
+		{
+			input:    `count <= -1.5`,
+			expected: caveat.Le("count", caveat.Number(-1.5)),
+		},
+		{
+			input:    `count > 10`,
+			expected: caveat.Gt("count", caveat.Number(10)),
+		},
+		{
+			input:    `time >= 2006-01-02T15:04:05Z`,
+			expected: caveat.Ge("time", caveat.Time(ts)),
+		},
+		{
+			input:    `version = 10`,
+			expected: caveat.Eq("version", caveat.String("10")),
+			encoded:  `version = "10"`,
+		},
+		{
+			input:    `id in (00123, 1e2)`,
+			expected: caveat.In("id", caveat.String("00123"), caveat.String("1e2")),
+			encoded:  `id in ("00123", "1e2")`,
+		},
+		{
+			input:    `version = "10"`,
+			expected: caveat.Eq("version", caveat.String("10")),
+		},
+		{
+			input:    `org in (org1,org2, "org 3")`,
+			expected: caveat.In("org", caveat.String("org1"), caveat.String("org2"), caveat.String("org 3")),
+			encoded:  `org in (org1, org2, "org 3")`,
+		},
+		{
+			input:    `app not in (sensitive_app)`,
+			expected: caveat.NotIn("app", caveat.String("sensitive_app")),
+		},
+		{
+			input:    `request.path matches /user/*`,
+			expected: caveat.Glob("request.path", "/user/*"),
+		},
+		{
+			input:    `request.path =~ "/user/.*"`,
+			expected: caveat.Regex("request.path", "/user/.*"),
+			encoded:  `request.path =~ /user/.*`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, err := caveat.Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse(%q): unexpected error: %v", tt.input, err)
+			}
+			if diff := cmp.Diff(tt.expected.String(), e.String()); diff != "" {
+				t.Fatalf("Parse(%q): (-want +got):\n%s", tt.input, diff)
+			}
+			if diff := cmp.Diff(tt.expected.Values, e.Values); diff != "" {
+				t.Fatalf("Parse(%q).Values: (-want +got):\n%s", tt.input, diff)
+			}
+			encoded := tt.encoded
+			if encoded == "" {
+				encoded = tt.input
+			}
+			if diff := cmp.Diff(encoded, string(e.Bytes())); diff != "" {
+				t.Fatalf("Bytes(): (-want +got):\n%s", diff)
+			}
+			rt, err := caveat.Parse(e.Bytes())
+			if err != nil {
+				t.Fatalf("Parse(%q): round-trip failed: %v", e.String(), err)
+			}
+			if diff := cmp.Diff(e.Values, rt.Values); diff != "" {
+				t.Fatalf("round-trip values: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []string{
+		``,
+		`org`,
+		`org =`,
+		`org ?? acme`,
+		`org not acme`,
+		`org in acme`,
+		`org in (a, b`,
+		`org = "acme`,
+		`org = acme extra`,
+		`path matches "[a"`,
+		`path =~ "("`,
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := caveat.Parse([]byte(input))
+			if !errors.Is(err, caveat.ErrSyntax) {
+				t.Fatalf("Parse(%q): expected ErrSyntax, got %v", input, err)
+			}
+			t.Logf("Parse(%q): %v", input, err)
+		})
+	}
+}
+
+func TestNumber_roundTrip(t *testing.T) {
+	for _, n := range []float64{0, -1.5, 1e300, 5e-324} {
+		e := caveat.Lt("amount", caveat.Number(n))
+		rt, err := caveat.Parse(e.Bytes())
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", e.Bytes(), err)
+		}
+		if diff := cmp.Diff(e.Values, rt.Values); diff != "" {
+			t.Fatalf("Parse(%q).Values: (-want +got):\n%s", e.Bytes(), diff)
+		}
+	}
+	for _, n := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Number(%v): expected a panic", n)
+				}
+			}()
+			caveat.Number(n)
+		}()
+	}
+}
@@ -0,0 +1,97 @@
+package mack
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ClearConcurrent clears the verified stack like [VerifiedStack.Clear], but evaluates predicates concurrently
+// using up to the given number of workers. If workers is less than 1, runtime.GOMAXPROCS(0) workers are used.
+// This is useful when a [PredicateChecker] consults a database or a remote service.
+//
+// Each predicate is evaluated with its own context derived from ctx. As soon as a predicate fails, the contexts of
+// all predicates that come after it in the stack are canceled, and no further predicates are started.
+// Predicates that come before it are allowed to finish, so the returned error is always the one for the
+// lowest-index failing predicate: the same error that [VerifiedStack.Clear] would have returned.
+// The PredicateChecker must be safe for concurrent use.
+func (v *VerifiedStack) ClearConcurrent(ctx context.Context, pcheck PredicateChecker, workers int) error {
+	predicates := v.stackPredicates()
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(predicates) {
+		workers = len(predicates)
+	}
+	cc := concurrentClear{
+		predicates: predicates,
+		pcheck:     pcheck,
+		failed:     len(predicates),
+		cancels:    make([]context.CancelFunc, len(predicates)),
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			cc.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return cc.err
+}
+
+type concurrentClear struct {
+	predicates []stackPredicate
+	pcheck     PredicateChecker
+
+	mu      sync.Mutex
+	next    int                  // index of the next predicate to evaluate
+	failed  int                  // index of the lowest failing predicate, len(predicates) if none
+	err     error                // error for the lowest failing predicate
+	cancels []context.CancelFunc // cancel functions for in-flight predicates
+}
+
+func (cc *concurrentClear) work(ctx context.Context) {
+	for {
+		i, pctx, ok := cc.claim(ctx)
+		if !ok {
+			return
+		}
+		_, err := checkPredicate(pctx, cc.predicates[i].Predicate, cc.pcheck)
+		cc.finish(i, err)
+	}
+}
+
+// claim reserves the next predicate to evaluate, returning false if there is no more work to do.
+func (cc *concurrentClear) claim(ctx context.Context) (int, context.Context, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.next >= cc.failed {
+		return 0, nil, false
+	}
+	i := cc.next
+	cc.next++
+	pctx, cancel := context.WithCancel(ctx)
+	cc.cancels[i] = cancel
+	return i, pctx, true
+}
+
+// finish records the result of predicate i. If it failed, and it is the lowest failure so far,
+// every in-flight predicate after it is canceled.
+func (cc *concurrentClear) finish(i int, err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.cancels[i]()
+	cc.cancels[i] = nil
+	if err == nil || i >= cc.failed {
+		return
+	}
+	cc.failed = i
+	cc.err = err
+	for j := i + 1; j < len(cc.cancels); j++ {
+		if cc.cancels[j] != nil {
+			cc.cancels[j]()
+		}
+	}
+}
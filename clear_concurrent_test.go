@@ -0,0 +1,69 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	macaroon "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestVerified_ClearConcurrent(t *testing.T) {
+	ctx := context.Background()
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: "slow-fail"},
+			{ID: "block"},
+			{ID: "ok"},
+			{
+				ID:         "{cK,userid == foo}",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "fast-fail"},
+				},
+			},
+		},
+	})
+	v := macaroon.InsecureVerifiedStack(fx.Stack)
+	var canceled atomic.Int32
+	checker := macaroon.PredicateCheckerFunc(func(ctx context.Context, p []byte) (bool, error) {
+		switch string(p) {
+		case "slow-fail":
+			time.Sleep(20 * time.Millisecond)
+			return false, nil
+		case "fast-fail":
+			return false, nil
+		case "block":
+			<-ctx.Done()
+			canceled.Add(1)
+			return false, ctx.Err()
+		default:
+			return true, nil
+		}
+	})
+	for i := 0; i < 10; i++ {
+		err := v.ClearConcurrent(ctx, checker, 4)
+		var pe interface{ Predicate() macaroon.Predicate }
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected predicate not satisfied error, got %v", err)
+		}
+		if got := string(pe.Predicate().CaveatID); got != "slow-fail" {
+			t.Fatalf("expected lowest-index failure 'slow-fail' to be reported, got '%s'", got)
+		}
+	}
+	if canceled.Load() != 10 {
+		t.Fatalf("expected blocked predicate to be canceled 10 times, got %d", canceled.Load())
+	}
+	t.Run("success", func(t *testing.T) {
+		ok := macaroon.PredicateCheckerFunc(func(context.Context, []byte) (bool, error) {
+			return true, nil
+		})
+		if err := v.ClearConcurrent(ctx, ok, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -43,10 +43,13 @@ func TestHmacSha256(t *testing.T) {
 }
 
 func TestBindForRequestHmacSHA256(t *testing.T) {
-	tm := mack.NewFromRaw(mack.Raw{
+	tm, err := mack.NewFromRaw(mack.Raw{
 		ID:        []byte(`id`),
 		Signature: []byte(`sig`),
 	})
+	if err != nil {
+		t.Fatalf("NewFromRaw: %v", err)
+	}
 	tests := []struct {
 		name      string
 		sig       []byte
@@ -116,13 +119,16 @@ func TestBindForRequestSHA256(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tm := mack.NewFromRaw(mack.Raw{
+			tm, err := mack.NewFromRaw(mack.Raw{
 				ID:        []byte(`id`),
 				Signature: tt.sig,
 			})
+			if err != nil {
+				t.Fatalf("NewFromRaw: %v", err)
+			}
 			sig := make([]byte, len(tt.sig))
 			copy(sig, tt.sig)
-			err := BindForRequestHmacSHA256(&tm, sig)
+			err = BindForRequestHmacSHA256(&tm, sig)
 			if err != nil && !tt.expectErr {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -2,6 +2,8 @@ package mack
 
 import (
 	"bytes"
+	"fmt"
+	"strconv"
 	"unsafe"
 )
 
@@ -11,27 +13,27 @@ const (
 )
 
 type macaroonData struct {
-	locSize     uint16
-	idSize      uint16
-	sigSize     uint16
-	caveatCount uint16
+	locSize     fieldSize
+	idSize      fieldSize
+	sigSize     fieldSize
+	caveatCount fieldSize
 	caveatSize  uint64
 	data        [1]byte
 }
 
 func (m *macaroonData) size() uintptr {
-	return macaroonDataOverhead + uintptr(m.locSize+m.idSize+m.sigSize) + uintptr(m.caveatSize)
+	return macaroonDataOverhead + uintptr(m.locSize) + uintptr(m.idSize) + uintptr(m.sigSize) + uintptr(m.caveatSize)
 }
 
 type caveatData struct {
-	idSize  uint16
-	vidSize uint16
-	locSize uint16
+	idSize  fieldSize
+	vidSize fieldSize
+	locSize fieldSize
 	data    [1]byte
 }
 
 func (c *caveatData) size() uintptr {
-	return caveatDataOverhead + uintptr(c.vidSize+c.idSize+c.locSize)
+	return caveatDataOverhead + uintptr(c.vidSize) + uintptr(c.idSize) + uintptr(c.locSize)
 }
 
 func (c *caveatData) thirdParty() bool {
@@ -40,7 +42,7 @@ func (c *caveatData) thirdParty() bool {
 
 // returns the bytes for HMAC, which is concat(vId,cId).
 func (c *caveatData) hmacData() []byte {
-	return unsafe.Slice(&c.data[0], c.vidSize+c.idSize)
+	return unsafe.Slice(&c.data[0], uintptr(c.vidSize)+uintptr(c.idSize))
 }
 
 func (c *caveatData) vid() []byte {
@@ -52,37 +54,49 @@ func (c *caveatData) cid() []byte {
 }
 
 func (c *caveatData) loc() []byte {
-	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(&c.data[0]), c.vidSize+c.idSize)), c.locSize)
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(&c.data[0]), uintptr(c.vidSize)+uintptr(c.idSize))), c.locSize)
 }
 
-func newMacaroonData(loc string, id []byte, sigSize int, rcs ...RawCaveat) *macaroonData {
+func newMacaroonData(loc string, id []byte, sigSize int, rcs ...RawCaveat) (*macaroonData, error) {
+	if err := checkFieldSize("location", len(loc)); err != nil {
+		return nil, err
+	}
+	if err := checkFieldSize("id", len(id)); err != nil {
+		return nil, err
+	}
+	if err := checkFieldSize("signature", sigSize); err != nil {
+		return nil, err
+	}
+	if err := checkRawCaveats(0, rcs); err != nil {
+		return nil, err
+	}
 	cavSize := rawCaveatsSize(rcs)
 	sz := macaroonDataOverhead + uintptr(len(loc)+len(id)+sigSize) + cavSize
 	data := make([]byte, sz)
 	nmd := (*macaroonData)(unsafe.Pointer(&data[0]))
-	nmd.locSize = safeUint16(len(loc))
+	nmd.locSize = fieldSize(len(loc))
 	data = data[macaroonDataOverhead:]
 	n := copy(data, loc)
-	nmd.idSize = safeUint16(len(id))
+	nmd.idSize = fieldSize(len(id))
 	n += copy(data[n:n+int(nmd.idSize)], id)
-	nmd.sigSize = safeUint16(sigSize)
-	nmd.caveatCount = safeUint16(len(rcs))
+	nmd.sigSize = fieldSize(sigSize)
+	nmd.caveatCount = fieldSize(len(rcs))
 	nmd.caveatSize = uint64(cavSize)
 	cavdata := data[n:]
 	for i := range rcs {
 		cp := (*caveatData)(unsafe.Pointer(&cavdata[0]))
-		cp.vidSize = safeUint16(len(rcs[i].VID))
+		cp.vidSize = fieldSize(len(rcs[i].VID))
 		copy(cp.vid(), rcs[i].VID)
 
-		cp.idSize = safeUint16(len(rcs[i].CID))
+		cp.idSize = fieldSize(len(rcs[i].CID))
 		copy(cp.cid(), rcs[i].CID)
 
-		cp.locSize = safeUint16(len(rcs[i].Location))
+		cp.locSize = fieldSize(len(rcs[i].Location))
 		copy(cp.loc(), rcs[i].Location)
 
 		cavdata = cavdata[cp.size():]
 	}
-	return nmd
+	return nmd, nil
 }
 
 func (m *macaroonData) clone() *macaroonData {
@@ -92,7 +106,10 @@ func (m *macaroonData) clone() *macaroonData {
 	return (*macaroonData)(unsafe.Pointer(&data[0]))
 }
 
-func (m *macaroonData) appendCaveats(s *Scheme, rcs ...RawCaveat) *macaroonData {
+func (m *macaroonData) appendCaveats(s *Scheme, rcs ...RawCaveat) (*macaroonData, error) {
+	if err := checkRawCaveats(int(m.caveatCount), rcs); err != nil {
+		return nil, err
+	}
 	bs := m.bytes()
 	cavSize := rawCaveatsSize(rcs)
 	data := make([]byte, len(bs)+int(cavSize))
@@ -103,13 +120,13 @@ func (m *macaroonData) appendCaveats(s *Scheme, rcs ...RawCaveat) *macaroonData
 	cavdata := data[n:]
 	for i := range rcs {
 		cp := (*caveatData)(unsafe.Pointer(&cavdata[0]))
-		cp.vidSize = safeUint16(len(rcs[i].VID))
+		cp.vidSize = fieldSize(len(rcs[i].VID))
 		copy(cp.vid(), rcs[i].VID)
 
-		cp.idSize = safeUint16(len(rcs[i].CID))
+		cp.idSize = fieldSize(len(rcs[i].CID))
 		copy(cp.cid(), rcs[i].CID)
 
-		cp.locSize = safeUint16(len(rcs[i].Location))
+		cp.locSize = fieldSize(len(rcs[i].Location))
 		copy(cp.loc(), rcs[i].Location)
 
 		cavdata = cavdata[cp.size():]
@@ -118,9 +135,75 @@ func (m *macaroonData) appendCaveats(s *Scheme, rcs ...RawCaveat) *macaroonData
 		}
 	}
 	nmd := (*macaroonData)(unsafe.Pointer(&data[0]))
-	nmd.caveatCount += safeUint16(len(rcs))
+	nmd.caveatCount += fieldSize(len(rcs))
+	nmd.caveatSize += uint64(cavSize)
+	return nmd, nil
+}
+
+// appendBuilt appends the caveats accumulated by a [Builder] in a single allocation.
+// The VID of each third-party caveat is its key encrypted in place with the signature before the caveat.
+func (m *macaroonData) appendBuilt(s *Scheme, bcs []builderCaveat) (*macaroonData, error) {
+	n := int(m.caveatCount)
+	if int64(n+len(bcs)) > MaxFieldSize {
+		return nil, fmt.Errorf("%w: too many caveats: %d exceeds the maximum of %d", ErrInvalidArgument, n+len(bcs), MaxFieldSize)
+	}
+	var cavSize uintptr
+	for i := range bcs {
+		c := &bcs[i]
+		vidSize := c.vidSize(s)
+		var field string
+		var size int
+		switch {
+		case int64(len(c.CID)) > MaxFieldSize:
+			field, size = "cid", len(c.CID)
+		case int64(vidSize) > MaxFieldSize:
+			field, size = "vid", vidSize
+		case int64(len(c.Location)) > MaxFieldSize:
+			field, size = "location", len(c.Location)
+		}
+		if field != "" {
+			return nil, checkFieldSize("caveat["+strconv.Itoa(n+i)+"]."+field, size)
+		}
+		cavSize += uintptr(len(c.CID)+vidSize+len(c.Location)) + caveatDataOverhead
+	}
+	bs := m.bytes()
+	data := make([]byte, len(bs)+int(cavSize))
+	start := copy(data, bs) - int(m.sigSize)
+	sig := data[len(data)-s.keySize:]
+	copy(sig, m.sig())
+	cavdata := data[start:]
+	for i := range bcs {
+		c := &bcs[i]
+		cp := (*caveatData)(unsafe.Pointer(&cavdata[0]))
+		cp.vidSize = fieldSize(c.vidSize(s))
+		if c.key != nil {
+			vid := cp.vid()
+			out, err := s.enc.Encrypt(vid[:0], c.key, sig)
+			if err != nil {
+				return nil, fmt.Errorf("macaroon: error encrypting: %w", err)
+			}
+			if len(out) != len(vid) || &out[0] != &vid[0] {
+				return nil, fmt.Errorf("macaroon: error encrypting: expected %d bytes of ciphertext, got %d", len(vid), len(out))
+			}
+		} else {
+			copy(cp.vid(), c.VID)
+		}
+
+		cp.idSize = fieldSize(len(c.CID))
+		copy(cp.cid(), c.CID)
+
+		cp.locSize = fieldSize(len(c.Location))
+		copy(cp.loc(), c.Location)
+
+		cavdata = cavdata[cp.size():]
+		if err := s.hmac.HMAC(sig, sig, cp.hmacData()); err != nil {
+			panic(err) // HMAC should never fail
+		}
+	}
+	nmd := (*macaroonData)(unsafe.Pointer(&data[0]))
+	nmd.caveatCount += fieldSize(len(bcs))
 	nmd.caveatSize += uint64(cavSize)
-	return nmd
+	return nmd, nil
 }
 
 func (m *macaroonData) bytes() []byte {
@@ -142,24 +225,46 @@ func (m *macaroonData) sig() []byte {
 }
 
 func (m *macaroonData) caveatStart() *byte {
-	return (*byte)(unsafe.Add(unsafe.Pointer(&m.data[0]), uintptr(m.locSize+m.idSize)))
+	return (*byte)(unsafe.Add(unsafe.Pointer(&m.data[0]), uintptr(m.locSize)+uintptr(m.idSize)))
 }
 
 func (m *macaroonData) caveats() []Caveat {
-	caveats := make([]Caveat, m.caveatCount)
-	cavdata := m.caveatStart()
-	var offset uintptr
-	for i := 0; i < int(m.caveatCount); i++ {
-		cp := (*caveatData)(unsafe.Add(unsafe.Pointer(cavdata), offset))
-		caveats[i] = Caveat{caveatData: cp}
-		offset += caveatDataOverhead + uintptr(cp.vidSize+cp.idSize+cp.locSize)
-		if offset > uintptr(m.caveatSize) {
-			panic("caveat size overflow")
-		}
+	caveats := make([]Caveat, 0, m.caveatCount)
+	for cur := m.cursor(); cur.next(); {
+		caveats = append(caveats, cur.caveat)
 	}
 	return caveats
 }
 
+// caveatCursor walks the caveats in the layout without allocating.
+type caveatCursor struct {
+	m      *macaroonData
+	offset uintptr
+	// index of the current caveat.
+	index int
+	// caveat is the current caveat.
+	caveat Caveat
+}
+
+func (m *macaroonData) cursor() caveatCursor {
+	return caveatCursor{m: m, index: -1}
+}
+
+// next advances the cursor to the next caveat, returning false if there are none left.
+func (c *caveatCursor) next() bool {
+	if c.index+1 >= int(c.m.caveatCount) {
+		return false
+	}
+	cp := (*caveatData)(unsafe.Add(unsafe.Pointer(c.m.caveatStart()), c.offset))
+	c.offset += cp.size()
+	if c.offset > uintptr(c.m.caveatSize) {
+		panic("caveat size overflow")
+	}
+	c.index++
+	c.caveat = Caveat{caveatData: cp}
+	return true
+}
+
 func (m *macaroonData) equal(o *macaroonData) bool {
 	return bytes.Equal(m.bytes(), o.bytes())
 }
@@ -171,12 +276,33 @@ func rawCaveatsSize(rcs []RawCaveat) (cavSize uintptr) {
 	return cavSize
 }
 
-func safeUint16(n int) uint16 {
-	if n > 65535 {
-		return 65535
+// checkFieldSize returns an error if a field of n bytes cannot be represented in the macaroon layout.
+func checkFieldSize(field string, n int) error {
+	if int64(n) > MaxFieldSize {
+		return fmt.Errorf("%w: %s is too large: %d bytes exceeds the maximum of %d", ErrInvalidArgument, field, n, MaxFieldSize)
 	}
-	if n < 0 {
-		return 0
+	return nil
+}
+
+// checkRawCaveats returns an error if the caveats cannot be appended to a macaroon which already has n caveats.
+func checkRawCaveats(n int, rcs []RawCaveat) error {
+	if int64(n+len(rcs)) > MaxFieldSize {
+		return fmt.Errorf("%w: too many caveats: %d exceeds the maximum of %d", ErrInvalidArgument, n+len(rcs), MaxFieldSize)
+	}
+	for i := range rcs {
+		var field string
+		var size int
+		switch {
+		case int64(len(rcs[i].CID)) > MaxFieldSize:
+			field, size = "cid", len(rcs[i].CID)
+		case int64(len(rcs[i].VID)) > MaxFieldSize:
+			field, size = "vid", len(rcs[i].VID)
+		case int64(len(rcs[i].Location)) > MaxFieldSize:
+			field, size = "location", len(rcs[i].Location)
+		default:
+			continue
+		}
+		return checkFieldSize("caveat["+strconv.Itoa(n+i)+"]."+field, size)
 	}
-	return uint16(n)
+	return nil
 }
@@ -0,0 +1,13 @@
+//go:build !mack_large
+
+package mack
+
+import "math"
+
+// fieldSize is the type of the length prefixes in the macaroon layout.
+type fieldSize = uint16
+
+// MaxFieldSize is the maximum size in bytes of a macaroon's location, ID, signature, or any part of a caveat,
+// and the maximum number of caveats in a macaroon.
+// Build with the mack_large tag to raise this limit, at the cost of a larger layout.
+const MaxFieldSize = math.MaxUint16
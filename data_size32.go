@@ -0,0 +1,13 @@
+//go:build mack_large
+
+package mack
+
+import "math"
+
+// fieldSize is the type of the length prefixes in the macaroon layout.
+type fieldSize = uint32
+
+// MaxFieldSize is the maximum size in bytes of a macaroon's location, ID, signature, or any part of a caveat,
+// and the maximum number of caveats in a macaroon.
+// This limit is raised by the mack_large build tag, for services which embed large structured caveats.
+const MaxFieldSize = math.MaxUint32
@@ -89,7 +89,11 @@ func helpGenerateMacaroon(tb testing.TB, caveats int) Macaroon {
 			CID: []byte(`9d864f22-48e7-401e-af01-e07032bb1846`),
 		}
 	}
-	return NewFromRaw(raw)
+	m, err := NewFromRaw(raw)
+	if err != nil {
+		tb.Fatalf("NewFromRaw: %v", err)
+	}
+	return m
 }
 
 func TestMacaroon_Clone_allocs(t *testing.T) {
@@ -131,6 +135,50 @@ func TestMacaroon_AddCaveat_allocs(t *testing.T) {
 	t.Logf("AllocsPerRun: %d", int(allocs))
 }
 
+func TestBuilder_Build_allocs(t *testing.T) {
+	m := helpGenerateMacaroon(t, 100)
+	sch, err := NewScheme(SchemeConfig{
+		HMACScheme:           testScheme{},
+		EncryptionScheme:     testScheme{},
+		BindForRequestScheme: testScheme{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create new scheme: %v", err)
+	}
+	predicate := []byte(`9d864f2248e7401eaf01e07032bb18469d864f2248e7401eaf01e07032bb1846`)
+	key := make([]byte, 32)
+	tests := []struct {
+		name  string
+		build func(b *Builder) *Builder
+	}{
+		{name: "first-party", build: func(b *Builder) *Builder {
+			return b.FirstParty(predicate)
+		}},
+		{name: "first-party-5", build: func(b *Builder) *Builder {
+			return b.FirstParty(predicate).FirstParty(predicate).FirstParty(predicate).FirstParty(predicate).FirstParty(predicate)
+		}},
+		{name: "third-party", build: func(b *Builder) *Builder {
+			return b.ThirdParty(key, predicate, "https://example.org")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(10*1024, func() {
+				_, err = tt.build(sch.NewBuilder(&m)).Build()
+				if err != nil {
+					t.Fatalf("unexpected error: %v/%v", err, errors.Unwrap(err))
+				}
+			})
+			const expected = 2 // builder, copy buffer
+			if allocs > expected {
+				writeHeapProfile(t)
+				t.Fatalf("allocs: %d > %d", int(allocs), expected)
+			}
+			t.Logf("AllocsPerRun: %d", int(allocs))
+		})
+	}
+}
+
 type testScheme struct{}
 
 func (t testScheme) BindForRequest(_ *Macaroon, _ []byte) error {
@@ -145,8 +193,8 @@ func (t testScheme) NonceSize() int {
 	return 0
 }
 
-func (t testScheme) Encrypt(_ []byte, in []byte, _ []byte) ([]byte, error) {
-	return in, nil
+func (t testScheme) Encrypt(out []byte, in []byte, _ []byte) ([]byte, error) {
+	return append(out, in...), nil
 }
 
 func (t testScheme) Decrypt(_ []byte, in []byte, _ []byte) ([]byte, error) {
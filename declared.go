@@ -0,0 +1,95 @@
+package mack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DeclaredPrefix is the prefix of a declared caveat, which has the form `declared <key> <value>`.
+const DeclaredPrefix = "declared "
+
+// DeclaredCaveat creates the predicate of a first-party caveat which declares the key has the value.
+// The key must not be empty or contain a space. The value may contain spaces.
+//
+// A declared caveat both restricts and conveys an attribute, ie: a third-party adds `declared user alice`
+// to its discharge to tell the target service who was authenticated.
+func DeclaredCaveat(key string, value string) ([]byte, error) {
+	if key == "" {
+		return nil, fmt.Errorf("%w: DeclaredCaveat: empty key", ErrInvalidArgument)
+	}
+	if strings.IndexByte(key, ' ') >= 0 {
+		return nil, fmt.Errorf("%w: DeclaredCaveat: key '%s' contains a space", ErrInvalidArgument, key)
+	}
+	bs := make([]byte, 0, len(DeclaredPrefix)+len(key)+1+len(value))
+	bs = append(bs, DeclaredPrefix...)
+	bs = append(bs, key...)
+	bs = append(bs, ' ')
+	bs = append(bs, value...)
+	return bs, nil
+}
+
+// parseDeclared returns the key and value of a declared caveat predicate.
+// It returns ok=false if the predicate is not a declared caveat, and an error if it is malformed.
+func parseDeclared(predicate []byte) (key string, value string, ok bool, err error) {
+	if !bytes.HasPrefix(predicate, []byte(DeclaredPrefix)) {
+		return "", "", false, nil
+	}
+	rest := predicate[len(DeclaredPrefix):]
+	i := bytes.IndexByte(rest, ' ')
+	if i <= 0 {
+		return "", "", true, fmt.Errorf("%w: malformed declared caveat: %s", ErrInvalidArgument, printableBytes(predicate))
+	}
+	return string(rest[:i]), string(rest[i+1:]), true, nil
+}
+
+// Declared contains the attributes declared by the caveats of a [VerifiedStack], by key.
+//
+// Declared is a [PredicateChecker] for declared caveats, which is satisfied when the caveat declares
+// the same value for the key. It may be registered with a [PredicateMux] for the [DeclaredPrefix].
+type Declared map[string]string
+
+// CheckPredicate checks that the declared caveat predicate declares the same value for its key.
+func (d Declared) CheckPredicate(_ context.Context, predicate []byte) (bool, error) {
+	key, value, ok, err := parseDeclared(predicate)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+	v, found := d[key]
+	return found && v == value, nil
+}
+
+// Declared returns the attributes declared by the first-party caveats of every macaroon in the stack,
+// the target and its discharges. It returns an error satisfying errors.Is(err, ErrDeclarationConflict)
+// if a key is declared more than once with different values, and an error satisfying
+// errors.Is(err, ErrInvalidArgument) if a declared caveat is malformed.
+//
+// Any holder of the target macaroon may add declared caveats to it, but may not remove the caveats
+// of a discharge. Only trust a key which the third-party always declares in its discharge, so that
+// a conflicting declaration by the holder fails, rather than being the only one.
+func (v *VerifiedStack) Declared() (Declared, error) {
+	d := make(Declared)
+	for i := range v.stack {
+		for cur := v.stack[i].data.cursor(); cur.next(); {
+			if cur.caveat.thirdParty() {
+				continue
+			}
+			key, value, ok, err := parseDeclared(cur.caveat.ID())
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if prev, found := d[key]; found && prev != value {
+				return nil, fmt.Errorf("%w: key '%s' declared as both '%s' and '%s'", ErrDeclarationConflict, key, prev, value)
+			}
+			d[key] = value
+		}
+	}
+	return d, nil
+}
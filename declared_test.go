@@ -0,0 +1,89 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestVerifiedStack_Declared(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "declared org acme"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://auth.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "declared user alice smith"},
+					{ID: "declared org acme"},
+				},
+			},
+		},
+	})
+	ctx := context.Background()
+	vs, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	declared, err := vs.Declared()
+	if err != nil {
+		t.Fatalf("Declared: %v", err)
+	}
+	if len(declared) != 2 || declared["user"] != "alice smith" || declared["org"] != "acme" {
+		t.Fatalf("Declared: unexpected attributes: %v", declared)
+	}
+	var mux mack.PredicateMux
+	if err = mux.Handle(mack.DeclaredPrefix, declared); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err = vs.Clear(ctx, &mux); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+
+	bob, err := mack.DeclaredCaveat("user", "bob")
+	if err != nil {
+		t.Fatalf("DeclaredCaveat: %v", err)
+	}
+	if ok, _ := declared.CheckPredicate(ctx, bob); ok {
+		t.Fatalf("CheckPredicate: expected a different value not to be satisfied")
+	}
+	target, err := fx.Scheme.AddFirstPartyCaveat(fx.Target, bob)
+	if err != nil {
+		t.Fatalf("AddFirstPartyCaveat: %v", err)
+	}
+	stack, err := fx.Scheme.PrepareStack(&target, fx.Discharge)
+	if err != nil {
+		t.Fatalf("PrepareStack: %v", err)
+	}
+	if vs, err = fx.Scheme.Verify(ctx, testhelpers.RootKey, stack); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err = vs.Declared(); !errors.Is(err, mack.ErrDeclarationConflict) {
+		t.Fatalf("Declared: expected ErrDeclarationConflict, got %v", err)
+	}
+}
+
+func TestDeclaredCaveat(t *testing.T) {
+	bs, err := mack.DeclaredCaveat("user", "alice")
+	if err != nil {
+		t.Fatalf("DeclaredCaveat: %v", err)
+	}
+	if string(bs) != "declared user alice" {
+		t.Fatalf("DeclaredCaveat: unexpected caveat: %q", bs)
+	}
+	for _, key := range []string{"", "user name"} {
+		if _, err = mack.DeclaredCaveat(key, "alice"); !errors.Is(err, mack.ErrInvalidArgument) {
+			t.Errorf("DeclaredCaveat(%q): expected ErrInvalidArgument, got %v", key, err)
+		}
+	}
+	d := mack.Declared{"user": "alice"}
+	for _, predicate := range []string{"declared user", "declared  alice"} {
+		if _, err = d.CheckPredicate(context.Background(), []byte(predicate)); !errors.Is(err, mack.ErrInvalidArgument) {
+			t.Errorf("CheckPredicate(%q): expected ErrInvalidArgument, got %v", predicate, err)
+		}
+	}
+}
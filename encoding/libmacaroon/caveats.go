@@ -0,0 +1,20 @@
+//go:build !go1.23
+
+package libmacaroon
+
+import "github.com/justenwalker/mack"
+
+// eachCaveat calls fn with the index and value of every caveat in m, stopping at the first error.
+func eachCaveat(m *mack.Macaroon, fn func(i int, c mack.Caveat) error) error {
+	for i, c := range m.Caveats() {
+		if err := fn(i, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// caveatCount returns the number of caveats in m.
+func caveatCount(m *mack.Macaroon) int {
+	return len(m.Caveats())
+}
@@ -0,0 +1,24 @@
+//go:build go1.23
+
+package libmacaroon
+
+import "github.com/justenwalker/mack"
+
+// eachCaveat calls fn with the index and value of every caveat in m, stopping at the first error.
+// It walks the caveats with [mack.Macaroon.AllCaveats], so encoding does not allocate a caveat slice.
+func eachCaveat(m *mack.Macaroon, fn func(i int, c mack.Caveat) error) error {
+	for i, c := range m.AllCaveats() {
+		if err := fn(i, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// caveatCount returns the number of caveats in m.
+func caveatCount(m *mack.Macaroon) (n int) {
+	for range m.AllCaveats() {
+		n++
+	}
+	return n
+}
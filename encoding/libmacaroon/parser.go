@@ -2,6 +2,7 @@ package libmacaroon
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/justenwalker/mack"
 )
@@ -12,7 +13,11 @@ var errNoData = errors.New("libmacaroon: no macaroon data")
 // it detects the encoding of format of the macaroon by inspecting the bytes.
 // It expects v1 binary format to be base-64 encoded, as it is the canonical representation.
 // All other formats should be in their canonical json or binary formats.
-type Parser struct{}
+// The Limits are applied to whichever format is detected.
+type Parser struct {
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
+}
 
 // DecodeMacaroon decodes a macaroon from the given binary or text data.
 // The parser attempts to detect the format of the macaroon.
@@ -22,7 +27,7 @@ type Parser struct{}
 //   - Parse as v2 and return
 //
 // 2. If the first byte is '{', then this is a json-formatted macaroon
-//   - Try to interpret as v2j, and return it if it succeeds.
+//   - Try to interpret as v2j, and return it if it succeeds, or exceeds the Limits.
 //   - Assume it is v1j and parse as that.
 //
 // 3. Assume binary format v1.
@@ -33,20 +38,24 @@ func (v *Parser) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
 	if len(bs) == 0 {
 		return errNoData
 	}
+	if err := v.Limits.CheckBytes(len(bs)); err != nil {
+		return fmt.Errorf("libmacaroon.Parser: %w", err)
+	}
 	if bs[0] == 2 { // version 2
-		return (V2{}).DecodeMacaroon(bs, m)
+		return (V2{Limits: v.Limits}).DecodeMacaroon(bs, m)
 	}
 	if bs[0] == '{' { // json object
-		if err := (V2J{}).DecodeMacaroon(bs, m); err == nil {
-			return nil
+		err := (V2J{Limits: v.Limits}).DecodeMacaroon(bs, m)
+		if err == nil || errors.Is(err, mack.ErrLimitExceeded) {
+			return err
 		}
-		return (V1J{}).DecodeMacaroon(bs, m)
+		return (V1J{Limits: v.Limits}).DecodeMacaroon(bs, m)
 	}
 	data, err := Base64DecodeLoose(string(bs))
 	if err != nil {
 		return err
 	}
-	return (V1{}).DecodeMacaroon(data, m)
+	return (V1{Limits: v.Limits}).DecodeMacaroon(data, m)
 }
 
 // DecodeStack decodes a stack macaroons from the given binary or text data.
@@ -57,7 +66,7 @@ func (v *Parser) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
 //   - Parse as v2 and return
 //
 // 2. If the first byte is '[', then this is a json-formatted list of macaroons
-//   - Try to interpret as an array of v2j, and return it if it succeeds.
+//   - Try to interpret as an array of v2j, and return it if it succeeds, or exceeds the Limits.
 //   - Assume it is an array of v1j and parse as that.
 //
 // 3. Assume binary format v1.
@@ -68,18 +77,22 @@ func (v *Parser) DecodeStack(bs []byte, stack *mack.Stack) error {
 	if len(bs) == 0 {
 		return errNoData
 	}
+	if err := v.Limits.CheckBytes(len(bs)); err != nil {
+		return fmt.Errorf("libmacaroon.Parser: %w", err)
+	}
 	if bs[0] == 2 { // version 2
-		return (V2{}).DecodeStack(bs, stack)
+		return (V2{Limits: v.Limits}).DecodeStack(bs, stack)
 	}
 	if bs[0] == '[' { // json array
-		if err := (V2J{}).DecodeStack(bs, stack); err == nil {
-			return nil
+		err := (V2J{Limits: v.Limits}).DecodeStack(bs, stack)
+		if err == nil || errors.Is(err, mack.ErrLimitExceeded) {
+			return err
 		}
-		return (V1J{}).DecodeStack(bs, stack)
+		return (V1J{Limits: v.Limits}).DecodeStack(bs, stack)
 	}
 	data, err := Base64DecodeLoose(string(bs))
 	if err != nil {
 		return err
 	}
-	return (V1{}).DecodeStack(data, stack)
+	return (V1{Limits: v.Limits}).DecodeStack(data, stack)
 }
@@ -6,6 +6,8 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -381,3 +383,60 @@ func (e PredicateChecker) CheckPredicate(ctx context.Context, predicate []byte)
 }
 
 var _ mack.PredicateChecker = Exact("")
+
+func TestParser_Limits(t *testing.T) {
+	sch := sensible.Scheme()
+	key := make([]byte, sch.KeySize())
+	m, err := sch.NewMacaroon("loc", []byte("id"), key, []byte("a = 1"), []byte("b = 2"), []byte("c = 3"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	stack := mack.Stack{m, m}
+	b64 := &libmacaroon.Base64{Encoding: base64.RawURLEncoding}
+	encoders := []encoding.StackEncoder{
+		libmacaroon.V1{OutputEncoder: b64},
+		libmacaroon.V2{},
+		libmacaroon.V1J{},
+		libmacaroon.V2J{},
+	}
+	tests := []struct {
+		name   string
+		limits mack.Limits
+		limit  string
+	}{
+		{name: "unlimited"},
+		{name: "within", limits: mack.Limits{MaxStackSize: 2, MaxCaveats: 3, MaxBytes: 4096}},
+		{name: "stack-size", limits: mack.Limits{MaxStackSize: 1}, limit: "MaxStackSize"},
+		{name: "caveats", limits: mack.Limits{MaxCaveats: 2}, limit: "MaxCaveats"},
+		{name: "bytes", limits: mack.Limits{MaxBytes: 16}, limit: "MaxBytes"},
+	}
+	for _, enc := range encoders {
+		bs, err := enc.EncodeStack(stack)
+		if err != nil {
+			t.Fatalf("%v: EncodeStack: %v", enc, err)
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%v/%s", enc, tt.name), func(t *testing.T) {
+				p := libmacaroon.Parser{Limits: tt.limits}
+				var got mack.Stack
+				err := p.DecodeStack(bs, &got)
+				if tt.limit == "" {
+					if err != nil {
+						t.Fatalf("DecodeStack: unexpected error: %v", err)
+					}
+					if len(got) != len(stack) {
+						t.Fatalf("DecodeStack: want %d macaroons, got %d", len(stack), len(got))
+					}
+					return
+				}
+				var le *mack.LimitError
+				if !errors.As(err, &le) || !errors.Is(err, mack.ErrLimitExceeded) {
+					t.Fatalf("DecodeStack: expected LimitError, got %v", err)
+				}
+				if le.Limit != tt.limit {
+					t.Fatalf("DecodeStack: expected limit %s, got %s", tt.limit, le.Limit)
+				}
+			})
+		}
+	}
+}
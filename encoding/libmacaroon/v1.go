@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"unsafe"
 
 	"github.com/justenwalker/mack"
@@ -19,6 +20,8 @@ var _ encoding.EncoderDecoder = V1{}
 type V1 struct {
 	OutputEncoder OutputEncoder
 	InputDecoder  InputDecoder
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
 }
 
 func (v V1) String() string {
@@ -27,21 +30,29 @@ func (v V1) String() string {
 
 // DecodeMacaroon decodes a macaroon from libmacaroon v1 binary format.
 func (v V1) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
+	if err := v.Limits.CheckBytes(len(bs)); err != nil {
+		return fmt.Errorf("v1.DecodeMacaroon: %w", err)
+	}
 	buf, err := decodeBuffer(v.InputDecoder, bs)
 	if err != nil {
 		return err
 	}
 	dec := NewV1Decoder(buf)
+	dec.Limits = v.Limits
 	return dec.DecodeMacaroon(m)
 }
 
 // DecodeStack decodes a stack of macaroons from libmacaroon v1 binary format.
 func (v V1) DecodeStack(bs []byte, stack *mack.Stack) error {
+	if err := v.Limits.CheckBytes(len(bs)); err != nil {
+		return fmt.Errorf("v1.DecodeStack: %w", err)
+	}
 	buf, err := decodeBuffer(v.InputDecoder, bs)
 	if err != nil {
 		return err
 	}
 	dec := NewV1Decoder(buf)
+	dec.Limits = v.Limits
 	return dec.DecodeStack(stack)
 }
 
@@ -113,11 +124,10 @@ func (enc *V1Encoder) EncodeMacaroon(m *mack.Macaroon) error {
 	if err := v1WritePacket(&bw, v1FieldIdentifier, m.ID()); err != nil {
 		return fmt.Errorf("v1.Encoder: failed to write field '%s': %w", v1FieldIdentifier, err)
 	}
-	cs := m.Caveats()
-	for i := range cs {
-		if err := v1WriteCaveat(&bw, &cs[i]); err != nil {
-			return err
-		}
+	if err := eachCaveat(m, func(_ int, c mack.Caveat) error {
+		return v1WriteCaveat(&bw, &c)
+	}); err != nil {
+		return err
 	}
 	if err := v1WritePacket(&bw, v1FieldSignature, m.Signature()); err != nil {
 		return fmt.Errorf("v1.Encoder: failed to write field '%s': %w", v1FieldSignature, err)
@@ -136,6 +146,8 @@ func (enc *V1Encoder) EncodeStack(stack mack.Stack) error {
 
 type V1Decoder struct {
 	reader *byteReader
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
 }
 
 func NewV1Decoder(bs []byte) *V1Decoder {
@@ -143,6 +155,9 @@ func NewV1Decoder(bs []byte) *V1Decoder {
 }
 
 func (dec *V1Decoder) DecodeMacaroon(m *mack.Macaroon) error {
+	if err := dec.Limits.CheckBytes(len(dec.reader.buf)); err != nil {
+		return fmt.Errorf("v1.DecodeMacaroon: %w", err)
+	}
 	var raw mack.Raw
 	var (
 		field v1FieldType
@@ -181,6 +196,9 @@ func (dec *V1Decoder) DecodeMacaroon(m *mack.Macaroon) error {
 				raw.Caveats = append(raw.Caveats, c)
 				c = mack.RawCaveat{}
 			}
+			if err = dec.Limits.CheckCaveats(len(raw.Caveats) + 1); err != nil {
+				return fmt.Errorf("v1.DecodeMacaroon: %w", err)
+			}
 			c.CID = data
 		case v1FieldCaveatLocation:
 			if c.Location != "" {
@@ -197,7 +215,9 @@ func (dec *V1Decoder) DecodeMacaroon(m *mack.Macaroon) error {
 				raw.Caveats = append(raw.Caveats, c)
 			}
 			raw.Signature = data
-			*m = mack.NewFromRaw(raw) // convert
+			if *m, err = mack.NewFromRaw(raw); err != nil { // convert
+				return fmt.Errorf("v1.DecodeMacaroon: %w", err)
+			}
 			return nil
 		default:
 			return fmt.Errorf("v1.DecodeMacaroon: unexpected field '%s': %w", field, err)
@@ -206,6 +226,9 @@ func (dec *V1Decoder) DecodeMacaroon(m *mack.Macaroon) error {
 }
 
 func (dec *V1Decoder) DecodeStack(stack *mack.Stack) error {
+	if err := dec.Limits.CheckBytes(len(dec.reader.buf)); err != nil {
+		return fmt.Errorf("v1.DecodeStack: %w", err)
+	}
 	var s mack.Stack
 	for {
 		var m mack.Macaroon
@@ -217,6 +240,9 @@ func (dec *V1Decoder) DecodeStack(stack *mack.Stack) error {
 			return err
 		}
 		s = append(s, m)
+		if err = dec.Limits.CheckStackSize(len(s)); err != nil {
+			return fmt.Errorf("v1.DecodeStack: %w", err)
+		}
 	}
 	*stack = s
 	return nil
@@ -262,7 +288,11 @@ func v1WriteLocation(w *byteWriter, loc string) error {
 func v1WritePacket(w *byteWriter, ft v1FieldType, data []byte) error {
 	var lengthBytes [2]byte
 	var lengthHex [4]byte
-	binary.BigEndian.PutUint16(lengthBytes[:], safeUint16(len(data)+6+len(ft)))
+	n := len(data) + 6 + len(ft)
+	if n > math.MaxUint16 {
+		return fmt.Errorf("%w: packet '%s' is too large: %d bytes exceeds the maximum of %d", mack.ErrInvalidArgument, ft, n, math.MaxUint16)
+	}
+	binary.BigEndian.PutUint16(lengthBytes[:], uint16(n))
 	hex.Encode(lengthHex[:], lengthBytes[:])
 	if _, err := w.Write(lengthHex[:]); err != nil { // Length
 		return err
@@ -302,22 +332,13 @@ func v1RawSizeBytes(m *mack.Macaroon) (sz int) {
 	sz += 6 + len(v1FieldLocation) + len(m.Location())
 	sz += 6 + len(v1FieldIdentifier) + len(m.ID())
 	sz += 6 + len(v1FieldSignature) + len(m.Signature())
-	for _, c := range m.Caveats() {
+	_ = eachCaveat(m, func(_ int, c mack.Caveat) error {
 		sz += 6 + len(v1FieldCid) + len(c.ID())
 		if vid := c.VID(); len(vid) > 0 {
 			sz += 6 + len(v1FieldVerification) + len(vid)
 			sz += 6 + len(v1FieldCaveatLocation) + len(c.Location())
 		}
-	}
+		return nil
+	})
 	return sz
 }
-
-func safeUint16(n int) uint16 {
-	if n > 65535 {
-		return 65535
-	}
-	if n < 0 {
-		return 0
-	}
-	return uint16(n)
-}
@@ -16,21 +16,26 @@ import (
 
 var _ encoding.EncoderDecoder = V1J{}
 
-type V1J struct{}
+type V1J struct {
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
+}
 
 func (V1J) String() string {
 	return "libmacaroon/v1j"
 }
 
 // DecodeMacaroon decodes a macaroon from libmacaroon v1 json format.
-func (V1J) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
+func (v V1J) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
 	dec := NewV1JDecoder(bs)
+	dec.Limits = v.Limits
 	return dec.DecodeMacaroon(m)
 }
 
 // DecodeStack decodes a macaroon stack from v1 json format.
-func (V1J) DecodeStack(bs []byte, stack *mack.Stack) error {
+func (v V1J) DecodeStack(bs []byte, stack *mack.Stack) error {
 	dec := NewV1JDecoder(bs)
+	dec.Limits = v.Limits
 	return dec.DecodeStack(stack)
 }
 
@@ -84,6 +89,8 @@ func (enc *V1JEncoder) EncodeStack(stack mack.Stack) error {
 
 type V1JDecoder struct {
 	buf []byte
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
 }
 
 func NewV1JDecoder(bs []byte) *V1JDecoder {
@@ -91,10 +98,16 @@ func NewV1JDecoder(bs []byte) *V1JDecoder {
 }
 
 func (dec *V1JDecoder) DecodeMacaroon(m *mack.Macaroon) error {
+	if err := dec.Limits.CheckBytes(len(dec.buf)); err != nil {
+		return fmt.Errorf("v1j.DecodeMacaroon: %w", err)
+	}
 	var js v1jMacaroonJSON
 	if err := json.Unmarshal(dec.buf, &js); err != nil {
 		return fmt.Errorf("v1j.DecodeMacaroon: failed to unmarshal json: %w", err)
 	}
+	if err := dec.Limits.CheckCaveats(len(js.Caveats)); err != nil {
+		return fmt.Errorf("v1j.DecodeMacaroon: %w", err)
+	}
 	if err := v1jMacaroonFromJSON(&js, m); err != nil {
 		return fmt.Errorf("v1j.DecodeMacaroon: failed to convert to macaroon: %w", err)
 	}
@@ -102,13 +115,24 @@ func (dec *V1JDecoder) DecodeMacaroon(m *mack.Macaroon) error {
 }
 
 func (dec *V1JDecoder) DecodeStack(stack *mack.Stack) error {
+	if err := dec.Limits.CheckBytes(len(dec.buf)); err != nil {
+		return fmt.Errorf("v1j.DecodeStack: %w", err)
+	}
 	var js []v1jMacaroonJSON
 	if err := json.Unmarshal(dec.buf, &js); err != nil {
 		return fmt.Errorf("v1j.DecodeStack: failed to unmarshal json: %w", err)
 	}
+	if err := dec.Limits.CheckStackSize(len(js)); err != nil {
+		return fmt.Errorf("v1j.DecodeStack: %w", err)
+	}
 	s := make(mack.Stack, len(js))
 	for i := range js {
-		return v1jMacaroonFromJSON(&js[i], &s[i])
+		if err := dec.Limits.CheckCaveats(len(js[i].Caveats)); err != nil {
+			return fmt.Errorf("v1j.DecodeStack: macaroon[%d]: %w", i, err)
+		}
+		if err := v1jMacaroonFromJSON(&js[i], &s[i]); err != nil {
+			return fmt.Errorf("v1j.DecodeStack: macaroon[%d]: decode failed: %w", i, err)
+		}
 	}
 	*stack = s
 	return nil
@@ -121,19 +145,22 @@ func v1jMacaroonToJSON(m *mack.Macaroon) (v1jMacaroonJSON, error) {
 	js := v1jMacaroonJSON{
 		Location:   m.Location(),
 		Identifier: string(m.ID()),
-		Caveats:    make([]v1jCaveatJSON, len(m.Caveats())),
+		Caveats:    make([]v1jCaveatJSON, caveatCount(m)),
 		Signature:  hex.EncodeToString(m.Signature()),
 	}
-	for i, c := range m.Caveats() {
+	if err := eachCaveat(m, func(i int, c mack.Caveat) error {
 		cid := c.ID()
 		if !utf8.Valid(cid) {
-			return v1jMacaroonJSON{}, errors.New("caveat id is not valid UTF-8")
+			return errors.New("caveat id is not valid UTF-8")
 		}
 		js.Caveats[i] = v1jCaveatJSON{
 			Location: c.Location(),
 			CID:      string(cid),
 			VID:      base64.RawURLEncoding.EncodeToString(c.VID()),
 		}
+		return nil
+	}); err != nil {
+		return v1jMacaroonJSON{}, err
 	}
 	return js, nil
 }
@@ -159,7 +186,9 @@ func v1jMacaroonFromJSON(js *v1jMacaroonJSON, m *mack.Macaroon) error {
 			Location: c.Location,
 		}
 	}
-	*m = mack.NewFromRaw(raw)
+	if *m, err = mack.NewFromRaw(raw); err != nil {
+		return fmt.Errorf("v1j.DecodeMacaroon: %w", err)
+	}
 	return nil
 }
 
@@ -16,6 +16,8 @@ var _ encoding.EncoderDecoder = V2{}
 type V2 struct {
 	OutputEncoder OutputEncoder
 	InputDecoder  InputDecoder
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
 }
 
 func (v V2) String() string {
@@ -24,21 +26,29 @@ func (v V2) String() string {
 
 // DecodeMacaroon decodes a macaroon from libmacaroon v2 binary format.
 func (v V2) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
+	if err := v.Limits.CheckBytes(len(bs)); err != nil {
+		return fmt.Errorf("v2.DecodeMacaroon: %w", err)
+	}
 	buf, err := decodeBuffer(v.InputDecoder, bs)
 	if err != nil {
 		return err
 	}
 	dec := NewV2Decoder(buf)
+	dec.Limits = v.Limits
 	return dec.DecodeMacaroon(m)
 }
 
 // DecodeStack decodes a stack of macaroons from libmacaroon v2 binary format.
 func (v V2) DecodeStack(bs []byte, stack *mack.Stack) error {
+	if err := v.Limits.CheckBytes(len(bs)); err != nil {
+		return fmt.Errorf("v2.DecodeStack: %w", err)
+	}
 	buf, err := decodeBuffer(v.InputDecoder, bs)
 	if err != nil {
 		return err
 	}
 	dec := NewV2Decoder(buf)
+	dec.Limits = v.Limits
 	return dec.DecodeStack(stack)
 }
 
@@ -134,11 +144,10 @@ func (enc *V2Encoder) EncodeMacaroon(m *mack.Macaroon) error {
 	if err := enc.writer.WriteByte(byte(v2FieldTypeEOS)); err != nil {
 		return err
 	}
-	cs := m.Caveats()
-	for i := range cs {
-		if err := enc.encodeCaveat(&cs[i]); err != nil {
-			return err
-		}
+	if err := eachCaveat(m, func(_ int, c mack.Caveat) error {
+		return enc.encodeCaveat(&c)
+	}); err != nil {
+		return err
 	}
 	if err := enc.writer.WriteByte(byte(v2FieldTypeEOS)); err != nil {
 		return err
@@ -210,6 +219,8 @@ func (ft v2FieldType) String() string {
 
 type V2Decoder struct {
 	reader *byteReader
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
 }
 
 func NewV2Decoder(bs []byte) *V2Decoder {
@@ -217,6 +228,9 @@ func NewV2Decoder(bs []byte) *V2Decoder {
 }
 
 func (dec *V2Decoder) DecodeMacaroon(m *mack.Macaroon) error {
+	if err := dec.Limits.CheckBytes(len(dec.reader.buf)); err != nil {
+		return fmt.Errorf("v2.DecodeMacaroon: %w", err)
+	}
 	ver, err := dec.reader.ReadByte()
 	if err != nil {
 		return fmt.Errorf("v2.DecodeMacaroon: could not read version byte: %w", err)
@@ -252,6 +266,9 @@ func (dec *V2Decoder) DecodeMacaroon(m *mack.Macaroon) error {
 		if !ok {
 			break
 		}
+		if err = dec.Limits.CheckCaveats(len(raw.Caveats)); err != nil {
+			return fmt.Errorf("v2.DecodeMacaroon: %w", err)
+		}
 	}
 
 	// Read Signature
@@ -263,11 +280,16 @@ func (dec *V2Decoder) DecodeMacaroon(m *mack.Macaroon) error {
 		return fmt.Errorf("v2.DecodeMacaroon: unexpected field type: %x", field)
 	}
 	raw.Signature = data
-	*m = mack.NewFromRaw(raw)
+	if *m, err = mack.NewFromRaw(raw); err != nil {
+		return fmt.Errorf("v2.DecodeMacaroon: %w", err)
+	}
 	return nil
 }
 
 func (dec *V2Decoder) DecodeStack(stack *mack.Stack) error {
+	if err := dec.Limits.CheckBytes(len(dec.reader.buf)); err != nil {
+		return fmt.Errorf("v2.DecodeStack: %w", err)
+	}
 	var s mack.Stack
 	for {
 		var m mack.Macaroon
@@ -279,6 +301,9 @@ func (dec *V2Decoder) DecodeStack(stack *mack.Stack) error {
 			return err
 		}
 		s = append(s, m)
+		if err = dec.Limits.CheckStackSize(len(s)); err != nil {
+			return fmt.Errorf("v2.DecodeStack: %w", err)
+		}
 	}
 	*stack = s
 	return nil
@@ -420,7 +445,7 @@ func v2RawSizeBytes(m *mack.Macaroon) int {
 	sz += 1 + binary.PutUvarint(varint[:], uint64(n)) + n
 	sz++ // eos
 
-	for _, c := range m.Caveats() {
+	_ = eachCaveat(m, func(_ int, c mack.Caveat) error {
 		// cl
 		if n = len(c.Location()); n > 0 {
 			sz += 1 + binary.PutUvarint(varint[:], uint64(n)) + n
@@ -434,7 +459,8 @@ func v2RawSizeBytes(m *mack.Macaroon) int {
 			sz += 1 + binary.PutUvarint(varint[:], uint64(n)) + n
 		}
 		sz++ // eos
-	}
+		return nil
+	})
 	sz++ // eos
 
 	// sig
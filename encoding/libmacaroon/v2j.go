@@ -16,21 +16,26 @@ import (
 
 var _ encoding.EncoderDecoder = V2J{}
 
-type V2J struct{}
+type V2J struct {
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
+}
 
 func (V2J) String() string {
 	return "libmacaroon/v2j"
 }
 
 // DecodeMacaroon decodes a macaroon from v2 json format.
-func (V2J) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
+func (v V2J) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
 	dec := NewV2JDecoder(bs)
+	dec.Limits = v.Limits
 	return dec.DecodeMacaroon(m)
 }
 
 // DecodeStack decodes a macaroon stack from v2 json format.
-func (V2J) DecodeStack(bs []byte, stack *mack.Stack) error {
+func (v V2J) DecodeStack(bs []byte, stack *mack.Stack) error {
 	dec := NewV2JDecoder(bs)
+	dec.Limits = v.Limits
 	return dec.DecodeStack(stack)
 }
 
@@ -80,6 +85,8 @@ func (enc *V2JEncoder) EncodeStack(stack mack.Stack) error {
 
 type V2JDecoder struct {
 	buf []byte
+	// Limits bounds the resources used to decode untrusted input.
+	Limits mack.Limits
 }
 
 func NewV2JDecoder(bs []byte) *V2JDecoder {
@@ -87,6 +94,9 @@ func NewV2JDecoder(bs []byte) *V2JDecoder {
 }
 
 func (dec *V2JDecoder) DecodeMacaroon(m *mack.Macaroon) error {
+	if err := dec.Limits.CheckBytes(len(dec.buf)); err != nil {
+		return fmt.Errorf("v2j.DecodeMacaroon: %w", err)
+	}
 	var js v2jMacaroonJSON
 	err := json.Unmarshal(dec.buf, &js)
 	if err != nil {
@@ -95,20 +105,32 @@ func (dec *V2JDecoder) DecodeMacaroon(m *mack.Macaroon) error {
 	if js.Version != 2 {
 		return fmt.Errorf("v2j.DecodeMacaroon: invalid version: %d", js.Version)
 	}
+	if err = dec.Limits.CheckCaveats(len(js.Caveats)); err != nil {
+		return fmt.Errorf("v2j.DecodeMacaroon: %w", err)
+	}
 	return v2jMacaroonFromJSON(&js, m)
 }
 
 func (dec *V2JDecoder) DecodeStack(stack *mack.Stack) error {
+	if err := dec.Limits.CheckBytes(len(dec.buf)); err != nil {
+		return fmt.Errorf("v2j.DecodeStack: %w", err)
+	}
 	var jsonstack []v2jMacaroonJSON
 	err := json.Unmarshal(dec.buf, &jsonstack)
 	if err != nil {
 		return fmt.Errorf("v2j.DecodeStack: failed to unmarshal json: %w", err)
 	}
+	if err = dec.Limits.CheckStackSize(len(jsonstack)); err != nil {
+		return fmt.Errorf("v2j.DecodeStack: %w", err)
+	}
 	s := make(mack.Stack, len(jsonstack))
 	for i := range jsonstack {
 		if jsonstack[i].Version != 2 {
 			return fmt.Errorf("v2j.DecodeStack: macaroon[%d]: invalid version: %d", i, jsonstack[i].Version)
 		}
+		if err = dec.Limits.CheckCaveats(len(jsonstack[i].Caveats)); err != nil {
+			return fmt.Errorf("v2j.DecodeStack: macaroon[%d]: %w", i, err)
+		}
 		if err = v2jMacaroonFromJSON(&jsonstack[i], &s[i]); err != nil {
 			return fmt.Errorf("v2j.DecodeStack: macaroon[%d]: decode failed: %w", i, err)
 		}
@@ -124,12 +146,13 @@ func v2jMacaroonToJSON(m *mack.Macaroon) v2jMacaroonJSON {
 	}
 	v2jSetData(m.ID(), &js.ID, &js.IDB64)
 	v2jSetData(m.Signature(), &js.Signature, &js.SignatureB64)
-	js.Caveats = make([]v2jCaveatJSON, len(m.Caveats()))
-	for i, c := range m.Caveats() {
+	js.Caveats = make([]v2jCaveatJSON, caveatCount(m))
+	_ = eachCaveat(m, func(i int, c mack.Caveat) error {
 		v2jSetData(c.ID(), &js.Caveats[i].ID, &js.Caveats[i].IDB64)
 		v2jSetData(c.VID(), &js.Caveats[i].Verification, &js.Caveats[i].VerificationB64)
 		js.Caveats[i].Location = c.Location()
-	}
+		return nil
+	})
 	return js
 }
 
@@ -160,7 +183,9 @@ func v2jMacaroonFromJSON(js *v2jMacaroonJSON, m *mack.Macaroon) error {
 	if err != nil {
 		return fmt.Errorf("v2j.DecodeMacaroon: failed to read signature: %w", err)
 	}
-	*m = mack.NewFromRaw(raw)
+	if *m, err = mack.NewFromRaw(raw); err != nil {
+		return fmt.Errorf("v2j.DecodeMacaroon: %w", err)
+	}
 	return nil
 }
 
@@ -15,6 +15,10 @@ const (
 	ErrPredicateNotSatisfied = Error("macaroon: predicate not satisfied")
 	ErrVerificationFailed    = Error("macaroon: verification failed")
 	ErrInvalidArgument       = Error("macaroon: invalid argument")
+	ErrRootKeyNotFound       = Error("macaroon: root key not found")
+	ErrRevoked               = Error("macaroon: revoked")
+	ErrLimitExceeded         = Error("macaroon: limit exceeded")
+	ErrDeclarationConflict   = Error("macaroon: conflicting declaration")
 )
 
 type predicateNotSatisfiedError struct {
@@ -33,33 +37,84 @@ func (m *predicateNotSatisfiedError) Is(err error) bool {
 	return errors.Is(err, ErrPredicateNotSatisfied)
 }
 
-type verificationError struct {
+//go:generate go tool -modfile=tools.mod golang.org/x/tools/cmd/stringer -type=VerificationReason -linecomment -output errors_string.go
+
+// VerificationReason classifies the cause of a [VerificationError].
+type VerificationReason int
+
+const (
+	VerificationUnknown           = VerificationReason(iota) // unknown
+	VerificationSignatureMismatch                            // signature mismatch
+	VerificationBindFailed                                   // bind for request failed
+	VerificationDecryptFailed                                // decrypt failed
+	VerificationMissingDischarge                             // missing discharge
+	VerificationDischargeCycle                               // discharge cycle
+	VerificationUnusedDischarge                              // unused discharge
+	VerificationReusedDischarge                              // reused discharge
+	VerificationLimitExceeded                                // limit exceeded
+	VerificationRevoked                                      // revoked
+	VerificationRootKeyNotFound                              // root key not found
+	VerificationSealMismatch                                 // seal mismatch
+)
+
+// VerificationError describes why a stack failed verification, and where in the stack it failed.
+// It satisfies errors.Is(err, ErrVerificationFailed), and wraps the error which caused the failure.
+type VerificationError struct {
+	// Reason classifies the cause of the failure.
+	Reason VerificationReason
+	// StackIndex is the position in the stack of the macaroon which failed verification. The target is 0.
+	StackIndex int
+	// CaveatIndex is the index of the caveat in the macaroon which caused the failure, or -1 if no caveat was involved.
+	CaveatIndex int
+	// CaveatID is the ID of the caveat which caused the failure, or nil if no caveat was involved.
+	CaveatID []byte
+
 	macaroon *Macaroon
 	err      error
 }
 
-func (m *verificationError) Error() string {
-	return string(ErrVerificationFailed)
+func (m *VerificationError) Error() string {
+	if m.err == nil {
+		return string(ErrVerificationFailed)
+	}
+	return string(ErrVerificationFailed) + ": " + m.err.Error()
 }
 
-func (m *verificationError) Macaroon() *Macaroon {
+// Macaroon returns the macaroon which failed verification.
+func (m *VerificationError) Macaroon() *Macaroon {
 	return m.macaroon
 }
 
-func (m *verificationError) Is(err error) bool {
+func (m *VerificationError) Is(err error) bool {
 	if errors.Is(err, ErrVerificationFailed) {
 		return true
 	}
 	return errors.Is(m.err, err)
 }
 
-func (m *verificationError) Unwrap() error {
+func (m *VerificationError) Unwrap() error {
 	return m.err
 }
 
-func validationError(m *Macaroon, err error) error {
-	return &verificationError{
-		macaroon: m,
-		err:      err,
+// verificationError creates a [VerificationError] for the macaroon at index vi of the stack, which did not involve a caveat.
+func verificationError(reason VerificationReason, m *Macaroon, vi int, err error) *VerificationError {
+	return &VerificationError{
+		Reason:      reason,
+		StackIndex:  vi,
+		CaveatIndex: -1,
+		macaroon:    m,
+		err:         err,
+	}
+}
+
+// caveatVerificationError creates a [VerificationError] for the caveat at index ci of the macaroon at index vi of the stack.
+func caveatVerificationError(reason VerificationReason, m *Macaroon, vi int, ci int, cid []byte, err error) *VerificationError {
+	return &VerificationError{
+		Reason:      reason,
+		StackIndex:  vi,
+		CaveatIndex: ci,
+		CaveatID:    cid,
+		macaroon:    m,
+		err:         err,
 	}
 }
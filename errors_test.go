@@ -0,0 +1,171 @@
+package mack_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestVerificationError(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	unrelated, err := fx.Scheme.NewMacaroon("loc", []byte("unrelated"), testhelpers.ThirdPartyKey, []byte("x = 1"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	revoked := mack.WithRevoker(mack.RevokerFunc(func(_ context.Context, m *mack.Macaroon) (bool, error) {
+		return string(m.ID()) == "3p", nil
+	}))
+	tests := []struct {
+		name        string
+		key         []byte
+		stack       mack.Stack
+		opts        []mack.VerifyOption
+		reason      mack.VerificationReason
+		stackIndex  int
+		caveatIndex int
+		caveatID    string
+		cause       error
+	}{
+		{
+			name:        "signature-mismatch",
+			key:         testhelpers.RootKey,
+			stack:       mack.Stack{unrelated},
+			reason:      mack.VerificationSignatureMismatch,
+			caveatIndex: -1,
+		},
+		{
+			name:        "decrypt-failed",
+			key:         testhelpers.ThirdPartyKey,
+			stack:       fx.Stack,
+			reason:      mack.VerificationDecryptFailed,
+			caveatIndex: 1,
+			caveatID:    "3p",
+		},
+		{
+			name:        "missing-discharge",
+			key:         testhelpers.RootKey,
+			stack:       fx.Stack[:1],
+			reason:      mack.VerificationMissingDischarge,
+			caveatIndex: 1,
+			caveatID:    "3p",
+		},
+		{
+			name:        "unused-discharge",
+			key:         testhelpers.RootKey,
+			stack:       mack.Stack{fx.Stack[0], fx.Stack[1], unrelated},
+			reason:      mack.VerificationUnusedDischarge,
+			stackIndex:  2,
+			caveatIndex: -1,
+		},
+		{
+			name:        "limit-exceeded",
+			key:         testhelpers.RootKey,
+			stack:       fx.Stack,
+			opts:        []mack.VerifyOption{mack.WithLimits(mack.Limits{MaxCaveats: 1})},
+			reason:      mack.VerificationLimitExceeded,
+			caveatIndex: -1,
+			cause:       mack.ErrLimitExceeded,
+		},
+		{
+			name:        "revoked",
+			key:         testhelpers.RootKey,
+			stack:       fx.Stack,
+			opts:        []mack.VerifyOption{revoked},
+			reason:      mack.VerificationRevoked,
+			stackIndex:  1,
+			caveatIndex: -1,
+			cause:       mack.ErrRevoked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fx.Scheme.Verify(context.Background(), tt.key, tt.stack, tt.opts...)
+			if !errors.Is(err, mack.ErrVerificationFailed) {
+				t.Fatalf("Verify: expected ErrVerificationFailed, got %v", err)
+			}
+			if tt.cause != nil && !errors.Is(err, tt.cause) {
+				t.Fatalf("Verify: expected %v, got %v", tt.cause, err)
+			}
+			var ve *mack.VerificationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Verify: expected a *VerificationError, got %T", err)
+			}
+			if ve.Reason != tt.reason {
+				t.Errorf("Reason: want %v, got %v", tt.reason, ve.Reason)
+			}
+			if ve.StackIndex != tt.stackIndex {
+				t.Errorf("StackIndex: want %d, got %d", tt.stackIndex, ve.StackIndex)
+			}
+			if ve.CaveatIndex != tt.caveatIndex {
+				t.Errorf("CaveatIndex: want %d, got %d", tt.caveatIndex, ve.CaveatIndex)
+			}
+			if string(ve.CaveatID) != tt.caveatID {
+				t.Errorf("CaveatID: want %q, got %q", tt.caveatID, ve.CaveatID)
+			}
+			if ve.Macaroon() != &tt.stack[ve.StackIndex] {
+				t.Errorf("Macaroon: expected the macaroon at stack index %d", ve.StackIndex)
+			}
+			if errors.Unwrap(err) == nil || err.Error() == string(mack.ErrVerificationFailed) {
+				t.Errorf("Error: expected the cause to be included: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerificationError_signatureNotDisclosed(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("a > 1"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	attenuated, err := sch.AddFirstPartyCaveat(&m, []byte("b > 2"))
+	if err != nil {
+		t.Fatalf("AddFirstPartyCaveat: %v", err)
+	}
+	// strip the last caveat, keeping the signature of the attenuated macaroon.
+	stripped, err := mack.NewFromRaw(mack.Raw{
+		ID:        m.ID(),
+		Location:  m.Location(),
+		Caveats:   []mack.RawCaveat{{CID: []byte("a > 1")}},
+		Signature: attenuated.Signature(),
+	})
+	if err != nil {
+		t.Fatalf("NewFromRaw: %v", err)
+	}
+	var events []mack.VerifyEvent
+	ctx := mack.WithVerifyObserver(context.Background(), mack.VerifyObserverFunc(func(_ context.Context, ev mack.VerifyEvent) {
+		events = append(events, ev)
+	}))
+	_, err = sch.Verify(ctx, testhelpers.RootKey, mack.Stack{stripped})
+	var ve *mack.VerificationError
+	if !errors.As(err, &ve) || ve.Reason != mack.VerificationSignatureMismatch {
+		t.Fatalf("Verify: expected a signature mismatch, got %v", err)
+	}
+	messages := []string{err.Error()}
+	for _, ev := range events {
+		if ev.Err != nil {
+			messages = append(messages, ev.Err.Error())
+		}
+	}
+	for _, sig := range [][]byte{m.Signature(), attenuated.Signature()} {
+		for _, msg := range messages {
+			if strings.Contains(msg, hex.EncodeToString(sig)) {
+				t.Fatalf("error discloses a signature: %s", msg)
+			}
+		}
+	}
+}
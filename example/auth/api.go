@@ -109,6 +109,17 @@ func (as *API) PostDischarge(w http.ResponseWriter, r *http.Request) {
 		as.writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	// declare the authenticated user, so the target service knows who the discharge was issued to.
+	declared, err := mack.DeclaredCaveat("user", ac.Username)
+	if err != nil {
+		as.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	m, err = as.scheme.AddFirstPartyCaveat(&m, declared)
+	if err != nil {
+		as.writeError(w, http.StatusBadRequest, err)
+		return
+	}
 	bs, err := msgpack.Encoding.EncodeMacaroon(&m)
 	if err != nil {
 		as.writeError(w, http.StatusBadRequest, err)
@@ -33,8 +33,9 @@ func (EncoderDecoder) DecodeMacaroon(bs []byte, m *mack.Macaroon) error {
 	if err := msgpack.Unmarshal(bs, &raw); err != nil {
 		return err
 	}
-	*m = mack.NewFromRaw(raw.Raw)
-	return nil
+	var err error
+	*m, err = mack.NewFromRaw(raw.Raw)
+	return err
 }
 
 func (EncoderDecoder) EncodeMacaroon(m *mack.Macaroon) ([]byte, error) {
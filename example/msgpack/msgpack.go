@@ -249,7 +249,9 @@ func (c *stackEncoder) DecodeMsgpack(decoder *msgpack.Decoder) (err error) {
 		if err = decoder.Decode(&raw); err != nil {
 			return fmt.Errorf("[%d]: %w", i, err)
 		}
-		stack[i] = mack.NewFromRaw(raw.Raw)
+		if stack[i], err = mack.NewFromRaw(raw.Raw); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
 	}
 	c.Stack = stack
 	return nil
@@ -144,19 +144,31 @@ func (as *API) PostOrgAppDo(w http.ResponseWriter, r *http.Request, org string,
 		as.writeError(w, http.StatusUnauthorized, err)
 		return
 	}
-	err = vc.Clear(r.Context(), PredicateChecker{
+	declared, err := vc.Declared()
+	if err != nil {
+		as.writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	var checker mack.PredicateMux
+	if err = checker.Handle(mack.DeclaredPrefix, declared); err != nil {
+		as.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	checker.HandleUnknown(PredicateChecker{
 		RequestContext: RequestContext{
 			Org:  org,
 			App:  app,
 			Time: time.Now(),
 		},
 	})
+	err = vc.Clear(r.Context(), &checker)
 	if err != nil {
 		as.writeError(w, http.StatusUnauthorized, err)
 		return
 	}
 	writeModel(w, http.StatusOK, OperationResponse{
-		"ok": true,
+		"ok":   true,
+		"user": declared["user"],
 	})
 }
 
@@ -0,0 +1,160 @@
+package mack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// DischargeGraph describes which discharge macaroons in a [Stack] are matched to which third-party caveats.
+// Caveats are matched the same way as by [Scheme.Verify]: to the first discharge macaroon whose ID is the caveat ID.
+// It does not verify any signatures.
+type DischargeGraph struct {
+	// Root is the node of the target macaroon. It is nil if the stack is empty.
+	Root *DischargeNode
+	// Nodes contains the node of each macaroon in the stack, by stack index.
+	Nodes []*DischargeNode
+	// Missing lists the third-party caveats that no discharge macaroon matches.
+	Missing []UndischargedCaveat
+	// Duplicates lists the stack index of each discharge macaroon with the same ID as an earlier discharge.
+	// These are never matched.
+	Duplicates []int
+	// Orphans lists the stack index of each discharge macaroon which is not a duplicate,
+	// but is not matched by any third-party caveat reachable from the target.
+	Orphans []int
+	// Reused lists the stack index of each discharge macaroon which is matched by more than one third-party caveat.
+	Reused []int
+	// Cycles lists the edges which match a discharge macaroon that is already being discharged, forming a cycle.
+	Cycles []*DischargeEdge
+}
+
+// DischargeNode is a macaroon in a [DischargeGraph].
+type DischargeNode struct {
+	// StackIndex is the position of the macaroon in the stack. The target is 0.
+	StackIndex int
+	// Macaroon is the macaroon in the stack.
+	Macaroon *Macaroon
+	// Edges contains an edge for each third-party caveat of the macaroon, in order.
+	Edges []*DischargeEdge
+	// Parents contains the edges which match this macaroon.
+	Parents []*DischargeEdge
+}
+
+// DischargeEdge links a third-party caveat to the discharge macaroon matched to it.
+type DischargeEdge struct {
+	// From is the node of the macaroon containing the caveat.
+	From *DischargeNode
+	// CaveatIndex is the index of the caveat in the macaroon.
+	CaveatIndex int
+	// Caveat is the third-party caveat.
+	Caveat Caveat
+	// Discharge is the node of the matched discharge macaroon, or nil if it is missing.
+	Discharge *DischargeNode
+	// Cycle is true if the discharge macaroon is an ancestor of this edge.
+	// Code that walks the graph should not follow the edge.
+	Cycle bool
+}
+
+// Graph builds the [DischargeGraph] of the stack.
+func (s Stack) Graph() *DischargeGraph {
+	g := &DischargeGraph{
+		Nodes: make([]*DischargeNode, len(s)),
+	}
+	if len(s) == 0 {
+		return g
+	}
+	for i := range s {
+		g.Nodes[i] = &DischargeNode{StackIndex: i, Macaroon: &s[i]}
+	}
+	first := make([]int, len(s)) // the stack index of the first discharge with the same ID.
+	for i := 1; i < len(s); i++ {
+		first[i] = i
+		for j := 1; j < i; j++ {
+			if first[j] == j && bytes.Equal(s[j].ID(), s[i].ID()) {
+				first[i] = j
+				g.Duplicates = append(g.Duplicates, i)
+				break
+			}
+		}
+	}
+	g.Root = g.Nodes[0]
+	onPath := make([]bool, len(s))
+	visited := make([]bool, len(s))
+	g.walk(s, g.Root, onPath, visited)
+	for i := 1; i < len(s); i++ {
+		n := g.Nodes[i]
+		switch {
+		case first[i] != i:
+		case len(n.Parents) == 0:
+			g.Orphans = append(g.Orphans, i)
+		case len(n.Parents) > 1:
+			g.Reused = append(g.Reused, i)
+		}
+	}
+	return g
+}
+
+func (g *DischargeGraph) walk(s Stack, n *DischargeNode, onPath []bool, visited []bool) {
+	onPath[n.StackIndex] = true
+	visited[n.StackIndex] = true
+	defer func() { onPath[n.StackIndex] = false }()
+	for cur := n.Macaroon.data.cursor(); cur.next(); {
+		if !cur.caveat.thirdParty() {
+			continue
+		}
+		e := &DischargeEdge{From: n, CaveatIndex: cur.index, Caveat: cur.caveat}
+		n.Edges = append(n.Edges, e)
+		d := matchDischarge(s, cur.caveat.ID())
+		if d < 0 {
+			g.Missing = append(g.Missing, UndischargedCaveat{StackIndex: n.StackIndex, Caveat: cur.caveat})
+			continue
+		}
+		e.Discharge = g.Nodes[d]
+		e.Discharge.Parents = append(e.Discharge.Parents, e)
+		if onPath[d] {
+			e.Cycle = true
+			g.Cycles = append(g.Cycles, e)
+			continue
+		}
+		if !visited[d] {
+			g.walk(s, e.Discharge, onPath, visited)
+		}
+	}
+}
+
+// matchDischarge returns the stack index of the first discharge with the ID, or -1.
+func matchDischarge(s Stack, id []byte) int {
+	for i := 1; i < len(s); i++ {
+		if bytes.Equal(s[i].ID(), id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid returns true if every third-party caveat is matched to a discharge, and every discharge is matched exactly once,
+// without cycles. A stack with a valid graph may still fail verification, since its signatures are not checked.
+func (g *DischargeGraph) Valid() bool {
+	return len(g.Missing) == 0 && len(g.Duplicates) == 0 && len(g.Orphans) == 0 && len(g.Reused) == 0 && len(g.Cycles) == 0
+}
+
+// Err returns an error describing each problem in the graph, or nil if it is [DischargeGraph.Valid].
+func (g *DischargeGraph) Err() error {
+	var errs []error
+	for _, m := range g.Missing {
+		errs = append(errs, fmt.Errorf("macaroon %d: missing discharge for caveat: %s", m.StackIndex, printableBytes(m.Caveat.ID())))
+	}
+	for _, i := range g.Duplicates {
+		errs = append(errs, fmt.Errorf("discharge macaroon %d: duplicate id: %s", i, printableBytes(g.Nodes[i].Macaroon.ID())))
+	}
+	for _, i := range g.Orphans {
+		errs = append(errs, fmt.Errorf("discharge macaroon %d: does not match any caveat: %s", i, printableBytes(g.Nodes[i].Macaroon.ID())))
+	}
+	for _, i := range g.Reused {
+		errs = append(errs, fmt.Errorf("discharge macaroon %d: matches %d caveats: %s", i, len(g.Nodes[i].Parents), printableBytes(g.Nodes[i].Macaroon.ID())))
+	}
+	for _, e := range g.Cycles {
+		errs = append(errs, fmt.Errorf("macaroon %d: caveat %d is discharged by its ancestor, macaroon %d", e.From.StackIndex, e.CaveatIndex, e.Discharge.StackIndex))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package mack_test
+
+import (
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestStack_Graph(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{
+				ID:         "3p-a",
+				ThirdParty: "https://a.example.org",
+				Caveats: []testhelpers.Caveat{
+					{
+						ID:         "3p-a-nested",
+						ThirdParty: "https://nested.example.org",
+					},
+				},
+			},
+			{ID: "a > 1"},
+			{
+				ID:         "3p-b",
+				ThirdParty: "https://b.example.org",
+			},
+		},
+	})
+	g := fx.Stack.Graph()
+	if !g.Valid() || g.Err() != nil {
+		t.Fatalf("Graph: expected a valid graph: %v", g.Err())
+	}
+	if len(g.Root.Edges) != 2 {
+		t.Fatalf("Graph: expected the target to have 2 edges, got %d", len(g.Root.Edges))
+	}
+	a := g.Root.Edges[0]
+	if a.CaveatIndex != 0 || a.Discharge == nil || string(a.Discharge.Macaroon.ID()) != "3p-a" {
+		t.Fatalf("Graph: unexpected first edge: %+v", a)
+	}
+	if len(a.Discharge.Edges) != 1 || a.Discharge.Edges[0].Discharge.StackIndex != 2 {
+		t.Fatalf("Graph: expected 3p-a to be discharged by macaroon 2")
+	}
+	b := g.Root.Edges[1]
+	if b.CaveatIndex != 2 || b.Discharge.StackIndex != 3 || b.Discharge.Parents[0] != b {
+		t.Fatalf("Graph: unexpected second edge: %+v", b)
+	}
+
+	unrelated, err := fx.Scheme.NewMacaroon("loc", []byte("unrelated"), testhelpers.ThirdPartyKey, []byte("x = 1"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	g = mack.Stack{fx.Stack[0], fx.Stack[1], unrelated, fx.Stack[1]}.Graph()
+	if g.Valid() || g.Err() == nil {
+		t.Fatalf("Graph: expected an invalid graph")
+	}
+	if len(g.Missing) != 2 || g.Missing[0].StackIndex != 1 || string(g.Missing[1].Caveat.ID()) != "3p-b" {
+		t.Fatalf("Graph: unexpected missing caveats: %+v", g.Missing)
+	}
+	if !equalInts(g.Duplicates, []int{3}) || !equalInts(g.Orphans, []int{2}) || len(g.Reused) != 0 {
+		t.Fatalf("Graph: unexpected duplicates=%v, orphans=%v, reused=%v", g.Duplicates, g.Orphans, g.Reused)
+	}
+	t.Log(g.Err())
+
+	if g = mack.Stack(nil).Graph(); g.Root != nil || !g.Valid() {
+		t.Fatalf("Graph: expected an empty graph")
+	}
+}
+
+func TestStack_Graph_cycle(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	newMacaroon := func(id string, key []byte, caveat string) mack.Macaroon {
+		m, err := sch.UnsafeRootMacaroon("loc", []byte(id), key)
+		if err != nil {
+			t.Fatalf("UnsafeRootMacaroon: %v", err)
+		}
+		if m, err = sch.AddThirdPartyCaveat(&m, testhelpers.ThirdPartyKey, []byte(caveat), "3p"); err != nil {
+			t.Fatalf("AddThirdPartyCaveat: %v", err)
+		}
+		return m
+	}
+	stack := mack.Stack{
+		newMacaroon("target", testhelpers.RootKey, "a"),
+		newMacaroon("a", testhelpers.ThirdPartyKey, "b"),
+		newMacaroon("b", testhelpers.ThirdPartyKey, "a"),
+	}
+	g := stack.Graph()
+	if len(g.Cycles) != 1 {
+		t.Fatalf("Graph: expected 1 cycle, got %d", len(g.Cycles))
+	}
+	if e := g.Cycles[0]; e.From.StackIndex != 2 || e.Discharge.StackIndex != 1 || !e.Cycle {
+		t.Fatalf("Graph: unexpected cycle edge from %d to %d", e.From.StackIndex, e.Discharge.StackIndex)
+	}
+	if !equalInts(g.Reused, []int{1}) {
+		t.Fatalf("Graph: expected macaroon 1 to be reused, got %v", g.Reused)
+	}
+	t.Log(g.Err())
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
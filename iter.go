@@ -0,0 +1,87 @@
+//go:build go1.23
+
+package mack
+
+import "iter"
+
+// AllCaveats returns an iterator over the index and value of every caveat in the macaroon.
+// Unlike Caveats, it walks the packed macaroon layout directly and does not allocate.
+func (m *Macaroon) AllCaveats() iter.Seq2[int, Caveat] {
+	return func(yield func(int, Caveat) bool) {
+		if m.IsZero() {
+			return
+		}
+		for cur := m.data.cursor(); cur.next(); {
+			if !yield(cur.index, cur.caveat) {
+				return
+			}
+		}
+	}
+}
+
+// FirstParty returns an iterator over the first-party caveats in the macaroon.
+// The index yielded is the position of the caveat among first-party caveats, matching FirstPartyCaveats.
+func (m *Macaroon) FirstParty() iter.Seq2[int, Caveat] {
+	return m.filterCaveats(false)
+}
+
+// ThirdParty returns an iterator over the third-party caveats in the macaroon.
+// The index yielded is the position of the caveat among third-party caveats, matching ThirdPartyCaveats.
+func (m *Macaroon) ThirdParty() iter.Seq2[int, Caveat] {
+	return m.filterCaveats(true)
+}
+
+func (m *Macaroon) filterCaveats(thirdParty bool) iter.Seq2[int, Caveat] {
+	return func(yield func(int, Caveat) bool) {
+		if m.IsZero() {
+			return
+		}
+		var i int
+		for cur := m.data.cursor(); cur.next(); {
+			if cur.caveat.thirdParty() != thirdParty {
+				continue
+			}
+			if !yield(i, cur.caveat) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// All returns an iterator over the index and a pointer to each macaroon in the stack.
+// The target macaroon is yielded first, followed by the discharge macaroons.
+func (s Stack) All() iter.Seq2[int, *Macaroon] {
+	return func(yield func(int, *Macaroon) bool) {
+		for i := range s {
+			if !yield(i, &s[i]) {
+				return
+			}
+		}
+	}
+}
+
+// AllPredicates returns an iterator over the first-party predicates in the verified stack.
+// It yields the same predicates, in the same order, as Predicates, without collecting them into a slice.
+func (v *VerifiedStack) AllPredicates() iter.Seq[Predicate] {
+	return func(yield func(Predicate) bool) {
+		for si := range v.stack {
+			m := &v.stack[si]
+			var i int
+			for cur := m.data.cursor(); cur.next(); {
+				if cur.caveat.thirdParty() {
+					continue
+				}
+				if !yield(Predicate{
+					MacaroonID: m.ID(),
+					CaveatID:   cur.caveat.ID(),
+					Index:      i,
+					Origin:     v.origin(si, cur.index),
+				}) {
+					return
+				}
+				i++
+			}
+		}
+	}
+}
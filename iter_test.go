@@ -0,0 +1,155 @@
+//go:build go1.23
+
+package mack_test
+
+import (
+	"bytes"
+	"iter"
+	"testing"
+
+	macaroon "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func iterFixture(tb testing.TB) testhelpers.Fixture {
+	tb.Helper()
+	return testhelpers.CreateTestFixture(tb, testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "{cK,userid == foo}",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "c > 3"},
+				},
+			},
+			{ID: "user = foo"},
+		},
+	})
+}
+
+func TestMacaroon_AllCaveats(t *testing.T) {
+	fx := iterFixture(t)
+	cs := fx.Target.Caveats()
+	var n int
+	for i, c := range fx.Target.AllCaveats() {
+		if i != n {
+			t.Fatalf("index: want %d, got %d", n, i)
+		}
+		if !bytes.Equal(c.ID(), cs[i].ID()) {
+			t.Fatalf("caveat[%d]: want %q, got %q", i, cs[i].ID(), c.ID())
+		}
+		n++
+	}
+	if n != len(cs) {
+		t.Fatalf("want %d caveats, got %d", len(cs), n)
+	}
+	for range fx.Target.AllCaveats() {
+		break
+	}
+	var zero macaroon.Macaroon
+	for range zero.AllCaveats() {
+		t.Fatalf("zero macaroon yielded a caveat")
+	}
+}
+
+func TestMacaroon_FirstParty_ThirdParty(t *testing.T) {
+	fx := iterFixture(t)
+	tests := []struct {
+		name  string
+		iter  func(m *macaroon.Macaroon) iter.Seq2[int, macaroon.Caveat]
+		slice func(m *macaroon.Macaroon) []macaroon.Caveat
+	}{
+		{
+			name:  "first-party",
+			iter:  (*macaroon.Macaroon).FirstParty,
+			slice: (*macaroon.Macaroon).FirstPartyCaveats,
+		},
+		{
+			name:  "third-party",
+			iter:  (*macaroon.Macaroon).ThirdParty,
+			slice: (*macaroon.Macaroon).ThirdPartyCaveats,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := tt.slice(fx.Target)
+			var n int
+			for i, c := range tt.iter(fx.Target) {
+				if i != n {
+					t.Fatalf("index: want %d, got %d", n, i)
+				}
+				if !bytes.Equal(c.ID(), expected[i].ID()) {
+					t.Fatalf("caveat[%d]: want %q, got %q", i, expected[i].ID(), c.ID())
+				}
+				n++
+			}
+			if n != len(expected) {
+				t.Fatalf("want %d caveats, got %d", len(expected), n)
+			}
+		})
+	}
+}
+
+func TestStack_All(t *testing.T) {
+	fx := iterFixture(t)
+	var n int
+	for i, m := range fx.Stack.All() {
+		if m != &fx.Stack[i] {
+			t.Fatalf("stack[%d]: expected pointer into the stack", i)
+		}
+		n++
+	}
+	if n != len(fx.Stack) {
+		t.Fatalf("want %d macaroons, got %d", len(fx.Stack), n)
+	}
+}
+
+func TestVerifiedStack_AllPredicates(t *testing.T) {
+	fx := iterFixture(t)
+	v := macaroon.InsecureVerifiedStack(fx.Stack)
+	expected := v.Predicates()
+	var n int
+	for p := range v.AllPredicates() {
+		if n >= len(expected) {
+			t.Fatalf("unexpected predicate: %v", p)
+		}
+		if p.String() != expected[n].String() {
+			t.Fatalf("predicate[%d]: want %v, got %v", n, expected[n], p)
+		}
+		n++
+	}
+	if n != len(expected) {
+		t.Fatalf("want %d predicates, got %d", len(expected), n)
+	}
+	for range v.AllPredicates() {
+		break
+	}
+}
+
+func TestIterators_allocs(t *testing.T) {
+	fx := iterFixture(t)
+	v := macaroon.InsecureVerifiedStack(fx.Stack)
+	var sink int
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, m := range fx.Stack.All() {
+			for i := range m.AllCaveats() {
+				sink += i
+			}
+			for i := range m.FirstParty() {
+				sink += i
+			}
+			for i := range m.ThirdParty() {
+				sink += i
+			}
+		}
+		for p := range v.AllPredicates() {
+			sink += p.Index
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+	_ = sink
+}
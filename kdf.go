@@ -0,0 +1,35 @@
+package mack
+
+import (
+	"fmt"
+)
+
+// DeriveKey derives a root key of [Scheme.KeySize] bytes from a secret using HKDF (RFC 5869)
+// instantiated with the scheme's [HMACScheme].
+//
+// The salt is optional; if empty, a string of KeySize zero bytes is used as specified by the RFC.
+// The info binds the derived key to its purpose, ie: a macaroon ID and a context label.
+// Since the HMAC output is exactly KeySize bytes, the expansion requires a single HMAC block.
+func (s *Scheme) DeriveKey(secret []byte, salt []byte, info []byte) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: DeriveKey: empty secret", ErrInvalidArgument)
+	}
+	if len(salt) == 0 {
+		salt = make([]byte, s.keySize)
+	}
+	// HKDF-Extract: PRK = HMAC(salt, IKM)
+	prk := s.getKeyBuffer()
+	defer s.releaseKeyBuffer(prk)
+	if err := s.hmac.HMAC(salt, *prk, secret); err != nil {
+		return nil, fmt.Errorf("macaroon: DeriveKey: extract failed: %w", err)
+	}
+	// HKDF-Expand: OKM = T(1) = HMAC(PRK, info || 0x01)
+	data := make([]byte, len(info)+1)
+	copy(data, info)
+	data[len(info)] = 0x01
+	key := make([]byte, s.keySize)
+	if err := s.hmac.HMAC(*prk, key, data); err != nil {
+		return nil, fmt.Errorf("macaroon: DeriveKey: expand failed: %w", err)
+	}
+	return key, nil
+}
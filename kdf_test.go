@@ -0,0 +1,61 @@
+package mack_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	macaroon "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestScheme_DeriveKey(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	unhex := func(s string) []byte {
+		bs, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("hex.DecodeString(%s): %v", s, err)
+		}
+		return bs
+	}
+	// Test vectors from RFC 5869 Appendix A (HMAC-SHA256), truncated to the first block.
+	tests := []struct {
+		name     string
+		ikm      []byte
+		salt     []byte
+		info     []byte
+		expected []byte
+	}{
+		{
+			name:     "rfc5869-case-1",
+			ikm:      bytes.Repeat([]byte{0x0b}, 22),
+			salt:     unhex("000102030405060708090a0b0c"),
+			info:     unhex("f0f1f2f3f4f5f6f7f8f9"),
+			expected: unhex("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf"),
+		},
+		{
+			name:     "rfc5869-case-3",
+			ikm:      bytes.Repeat([]byte{0x0b}, 22),
+			expected: unhex("8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := sch.DeriveKey(tt.ikm, tt.salt, tt.info)
+			if err != nil {
+				t.Fatalf("DeriveKey: unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, key); diff != "" {
+				t.Fatalf("DeriveKey: (-want +got):\n%s", diff)
+			}
+		})
+	}
+	t.Run("empty-secret", func(t *testing.T) {
+		if _, err := sch.DeriveKey(nil, nil, nil); !errors.Is(err, macaroon.ErrInvalidArgument) {
+			t.Fatalf("expected ErrInvalidArgument, got %v", err)
+		}
+	})
+}
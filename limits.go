@@ -0,0 +1,97 @@
+package mack
+
+import (
+	"fmt"
+)
+
+// Limits bounds the resources consumed when decoding and verifying untrusted macaroons.
+// A zero value for any field means the corresponding resource is unlimited.
+//
+// Limits are honoured by [Scheme.Verify] when given with [WithLimits], and by the decoders in the
+// encoding/libmacaroon package.
+type Limits struct {
+	// MaxStackSize is the maximum number of macaroons in a stack, including the target.
+	MaxStackSize int
+	// MaxCaveats is the maximum number of caveats in any single macaroon.
+	MaxCaveats int
+	// MaxBytes is the maximum total size of a stack in bytes.
+	// Decoders apply it to the size of their encoded input.
+	MaxBytes int
+	// MaxDepth is the maximum nesting depth of third-party caveats.
+	// A stack whose target has a third-party caveat discharged by a macaroon without third-party caveats has a depth of 1.
+	MaxDepth int
+	// MaxOperations is the maximum number of HMAC and decrypt operations performed while verifying a stack.
+	// It bounds the work done for a stack whose discharges are reused by many third-party caveats.
+	MaxOperations int
+}
+
+// LimitError is returned when one of the [Limits] is exceeded. It satisfies errors.Is(err, ErrLimitExceeded).
+type LimitError struct {
+	// Limit is the name of the field in Limits which was exceeded, ie: "MaxCaveats".
+	Limit string
+	// Max is the configured limit.
+	Max int
+	// Value is the value which exceeded the limit.
+	Value int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s: %d > %d", ErrLimitExceeded, e.Limit, e.Value, e.Max)
+}
+
+func (e *LimitError) Is(err error) bool {
+	return err == ErrLimitExceeded //nolint:errorlint // sentinel comparison
+}
+
+func checkLimit(name string, limit int, n int) error {
+	if limit > 0 && n > limit {
+		return &LimitError{Limit: name, Max: limit, Value: n}
+	}
+	return nil
+}
+
+// CheckStackSize returns a [LimitError] if a stack of n macaroons exceeds MaxStackSize.
+func (l Limits) CheckStackSize(n int) error {
+	return checkLimit("MaxStackSize", l.MaxStackSize, n)
+}
+
+// CheckCaveats returns a [LimitError] if a macaroon with n caveats exceeds MaxCaveats.
+func (l Limits) CheckCaveats(n int) error {
+	return checkLimit("MaxCaveats", l.MaxCaveats, n)
+}
+
+// CheckBytes returns a [LimitError] if n bytes exceeds MaxBytes.
+func (l Limits) CheckBytes(n int) error {
+	return checkLimit("MaxBytes", l.MaxBytes, n)
+}
+
+// CheckDepth returns a [LimitError] if a third-party caveat nesting depth of n exceeds MaxDepth.
+func (l Limits) CheckDepth(n int) error {
+	return checkLimit("MaxDepth", l.MaxDepth, n)
+}
+
+// CheckStack returns a [LimitError] if the stack exceeds MaxStackSize, MaxCaveats or MaxBytes.
+// The nesting depth and number of operations are only known while verifying, so they are checked by [Scheme.Verify].
+func (l Limits) CheckStack(stack Stack) error {
+	if err := l.CheckStackSize(len(stack)); err != nil {
+		return err
+	}
+	var n int
+	for i := range stack {
+		if err := l.CheckCaveats(int(stack[i].data.caveatCount)); err != nil {
+			return err
+		}
+		n += int(stack[i].data.size())
+	}
+	return l.CheckBytes(n)
+}
+
+// WithLimits checks the stack against the limits before verifying it,
+// and bounds the nesting depth of third-party caveats and the number of operations during verification.
+// If a limit is exceeded, the error satisfies both errors.Is(err, ErrVerificationFailed)
+// and errors.Is(err, ErrLimitExceeded), and may be inspected with errors.As as a [*LimitError].
+func WithLimits(l Limits) VerifyOption {
+	return func(o *verifyOptions) {
+		o.limits = l
+	}
+}
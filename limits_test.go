@@ -0,0 +1,61 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestScheme_Verify_WithLimits(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "org = acme"},
+			{
+				ID:         "3p-a",
+				ThirdParty: "https://a.example.org",
+				Caveats: []testhelpers.Caveat{
+					{
+						ID:         "3p-b",
+						ThirdParty: "https://b.example.org",
+					},
+				},
+			},
+		},
+	})
+	tests := []struct {
+		name   string
+		limits mack.Limits
+		limit  string
+	}{
+		{name: "unlimited"},
+		{name: "within", limits: mack.Limits{MaxStackSize: 3, MaxCaveats: 2, MaxBytes: 4096, MaxDepth: 2, MaxOperations: 8}},
+		{name: "stack-size", limits: mack.Limits{MaxStackSize: 2}, limit: "MaxStackSize"},
+		{name: "caveats", limits: mack.Limits{MaxCaveats: 1}, limit: "MaxCaveats"},
+		{name: "bytes", limits: mack.Limits{MaxBytes: 64}, limit: "MaxBytes"},
+		{name: "depth", limits: mack.Limits{MaxDepth: 1}, limit: "MaxDepth"},
+		{name: "operations", limits: mack.Limits{MaxOperations: 7}, limit: "MaxOperations"},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithLimits(tt.limits))
+			if tt.limit == "" {
+				if err != nil {
+					t.Fatalf("Verify: unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, mack.ErrVerificationFailed) || !errors.Is(err, mack.ErrLimitExceeded) {
+				t.Fatalf("Verify: expected ErrVerificationFailed and ErrLimitExceeded, got %v", err)
+			}
+			var le *mack.LimitError
+			if !errors.As(err, &le) || le.Limit != tt.limit {
+				t.Fatalf("Verify: expected %s to be exceeded, got %v", tt.limit, err)
+			}
+		})
+	}
+}
@@ -3,28 +3,34 @@ package mack
 import (
 	"bytes"
 	"crypto/hmac"
-	"encoding/hex"
+	"errors"
 	"fmt"
 )
 
+// Macaroon is an immutable bearer credential. A Macaroon may be shared by concurrent goroutines.
 type Macaroon struct {
-	caveats []Caveat
-
-	// data contains the full content of the macaroon and its caveats
+	// data contains the full content of the macaroon and its caveats. It is never modified.
 	data *macaroonData
 }
 
+// fromData creates a Macaroon from its data.
+func fromData(data *macaroonData) Macaroon {
+	return Macaroon{data: data}
+}
+
 // newMacaroon creates a new Macaroon with the given scheme, key, id, and location.
 func newMacaroon(s *Scheme, key []byte, id []byte, loc string) (Macaroon, error) {
 	// newMacaroon(k, id , L)
 	//	sig := MAC(k, id )
 	//	return macaroon@L〈id , [ ], sig〉
-	var m Macaroon
-	m.data = newMacaroonData(loc, id, s.keySize)
-	if err := s.hmac.HMAC(key, m.data.sig(), id); err != nil {
+	data, err := newMacaroonData(loc, id, s.keySize)
+	if err != nil {
+		return Macaroon{}, err
+	}
+	if err = s.hmac.HMAC(key, data.sig(), id); err != nil {
 		return Macaroon{}, err
 	}
-	return m, nil
+	return fromData(data), nil
 }
 
 // Clone create a clone of the given Macaroon.
@@ -33,7 +39,7 @@ func Clone(m *Macaroon) Macaroon {
 	if m == nil {
 		return Macaroon{}
 	}
-	return Macaroon{data: m.data.clone()}
+	return fromData(m.data.clone())
 }
 
 // IsZero returns true if the macaroon represents the Zero-value macaroon.
@@ -60,19 +66,12 @@ func (m *Macaroon) Signature() []byte {
 }
 
 // Caveats returns all macaroon caveats.
+// The slice is newly allocated by each call; use AllCaveats to walk the caveats without allocating.
 func (m *Macaroon) Caveats() []Caveat {
-	if m.caveats == nil {
-		m.caveats = m.data.caveats()
-	}
-	return m.caveats
-}
-
-func (m *Macaroon) caveatAt(i int) *Caveat {
-	cs := m.Caveats()
-	if len(cs) == 0 {
+	if m.IsZero() {
 		return nil
 	}
-	return &cs[i]
+	return m.data.caveats()
 }
 
 // Equal tests if a macaroon is exactly equal to another macaroon.
@@ -90,10 +89,10 @@ func (m *Macaroon) Equal(o *Macaroon) bool {
 // A first-party caveat is a caveat that does not include a verifier ID.
 // First-party caveats are evaluated by the target service.
 func (m *Macaroon) FirstPartyCaveats() []Caveat {
-	fpc := make([]Caveat, 0, len(m.Caveats()))
-	for _, c := range m.Caveats() {
-		if !c.thirdParty() {
-			fpc = append(fpc, c)
+	fpc := make([]Caveat, 0, m.data.caveatCount)
+	for cur := m.data.cursor(); cur.next(); {
+		if !cur.caveat.thirdParty() {
+			fpc = append(fpc, cur.caveat)
 		}
 	}
 	return fpc
@@ -103,16 +102,16 @@ func (m *Macaroon) FirstPartyCaveats() []Caveat {
 // A third-party caveat is a caveat that includes a verifier ID.
 // Third-party caveats are discharged by a third-party service with a Discharge Macaroon.
 func (m *Macaroon) ThirdPartyCaveats() []Caveat {
-	fpc := make([]Caveat, 0, len(m.Caveats()))
-	for _, c := range m.Caveats() {
-		if c.thirdParty() {
-			fpc = append(fpc, c)
+	tpc := make([]Caveat, 0, m.data.caveatCount)
+	for cur := m.data.cursor(); cur.next(); {
+		if cur.caveat.thirdParty() {
+			tpc = append(tpc, cur.caveat)
 		}
 	}
-	return fpc
+	return tpc
 }
 
-func (m *Macaroon) addFirstPartyCaveat(s *Scheme, predicate []byte) Macaroon {
+func (m *Macaroon) addFirstPartyCaveat(s *Scheme, predicate []byte) (Macaroon, error) {
 	return m.addCaveats(s, RawCaveat{
 		CID: predicate,
 	})
@@ -127,20 +126,38 @@ func (m *Macaroon) addThirdPartyCaveat(s *Scheme, cKey []byte, cID []byte, cLoc
 		CID:      cID,
 		VID:      vID,
 		Location: cLoc,
-	}), nil
+	})
 }
 
-func (m *Macaroon) addCaveats(s *Scheme, rcs ...RawCaveat) Macaroon {
-	nmd := m.data.appendCaveats(s, rcs...)
-	return Macaroon{
-		data: nmd,
+func (m *Macaroon) addCaveats(s *Scheme, rcs ...RawCaveat) (Macaroon, error) {
+	nmd, err := m.data.appendCaveats(s, rcs...)
+	if err != nil {
+		return Macaroon{}, err
 	}
+	return fromData(nmd), nil
 }
 
 // verify the macaroon.
 // The key is the key secret key used to create the root macaroon.
 // the sig is an optional buffer used for calculating the signatures, if not provided, it will allocate a buffer.
-func (m *Macaroon) verify(s *Scheme, stack Stack, key []byte, sigbuf []byte, v *verifyContext, vi int, discharged []byte) error {
+// vi is the index of the macaroon in the stack, and discharged counts the number of times each discharge macaroon was used.
+// depth is the nesting depth of the macaroon in the discharge chain, which may not exceed the MaxDepth limit, if it is non-zero.
+func (m *Macaroon) verify(vr *verifier, key []byte, sigbuf []byte, vi int, discharged []byte, depth int) error {
+	vr.observe(VerifyEvent{Kind: VerifyEventMacaroonStart, StackIndex: vi, CaveatIndex: -1, Depth: depth})
+	err := vr.checkContext()
+	if err == nil {
+		err = m.verifySignature(vr, key, sigbuf, vi, discharged, depth)
+	}
+	if err != nil {
+		vr.failedAt(vi, -1)
+	}
+	vr.observe(VerifyEvent{Kind: VerifyEventMacaroonEnd, StackIndex: vi, CaveatIndex: -1, Depth: depth, Err: err})
+	return err
+}
+
+func (m *Macaroon) verifySignature(vr *verifier, key []byte, sigbuf []byte, vi int, discharged []byte, depth int) error {
+	s := vr.s
+	v := vr.trace
 	var err error
 	defer func() {
 		if err != nil {
@@ -148,38 +165,93 @@ func (m *Macaroon) verify(s *Scheme, stack Stack, key []byte, sigbuf []byte, v *
 		}
 	}()
 	if len(key) != s.keySize {
-		return fmt.Errorf("%w: invalid key size. need=%d, got=%d", ErrInvalidArgument, s.keySize, len(key))
+		err = fmt.Errorf("%w: invalid key size. need=%d, got=%d", ErrInvalidArgument, s.keySize, len(key))
+		return err
 	}
 	if len(sigbuf) != s.keySize {
 		sigbuf = make([]byte, s.keySize)
 	}
+	if err = vr.spend(); err != nil {
+		err = verificationError(VerificationLimitExceeded, m, vi, err)
+		return err
+	}
+	var sealKey *[]byte
+	if m.sealed() { // derive the seal key before the key is overwritten.
+		sealKey = s.getKeyBuffer()
+		defer s.releaseKeyBuffer(sealKey)
+		for i := 0; i < 2; i++ { // HKDF extract and expand
+			if err = vr.spend(); err != nil {
+				err = verificationError(VerificationLimitExceeded, m, vi, err)
+				return err
+			}
+		}
+		if err = s.sealKey(key, *sealKey); err != nil {
+			return err
+		}
+	}
 	vo := v.traceRootKey(vi, key, m.ID())
 	if err = s.hmac.HMAC(key, sigbuf, m.ID()); err != nil {
-		return fmt.Errorf("error executing hmac: %w", err)
+		err = fmt.Errorf("error executing hmac: %w", err)
+		return err
 	}
 	vo.setResult(sigbuf)
-	for i := range m.Caveats() {
-		err = m.verifyCaveat(s, stack, sigbuf, m.caveatAt(i), v, vi, discharged)
+	for cur := m.data.cursor(); cur.next(); {
+		if err = vr.checkContext(); err == nil && sealKey != nil && !cur.caveat.thirdParty() && isSeal(cur.caveat.ID()) {
+			err = vr.checkSeal(m, *sealKey, sigbuf, &cur.caveat, cur.index, vi)
+		}
+		if err == nil {
+			err = m.verifyCaveat(vr, sigbuf, &cur.caveat, cur.index, vi, discharged, depth)
+		}
 		if err != nil {
+			vr.failedAt(vi, cur.index)
 			return err
 		}
 	}
-	target := stack.Target()
+	target := vr.stack.Target()
 	if m != target {
 		vo = v.trace(vi, TraceOpBind, target.Signature(), sigbuf)
 		err = s.bfr.BindForRequest(target, sigbuf)
 		vo.setResult(sigbuf)
 		if err != nil {
-			return validationError(m, fmt.Errorf("macaroon.verify: could not get request signature: %w", err))
+			err = verificationError(VerificationBindFailed, m, vi, fmt.Errorf("macaroon.verify: could not get request signature: %w", err))
+			return err
 		}
+		vr.observe(VerifyEvent{Kind: VerifyEventBind, StackIndex: vi, CaveatIndex: -1, Depth: depth})
 	}
 	if !hmac.Equal(m.data.sig(), sigbuf) {
-		return validationError(m, fmt.Errorf("macaroon.verify: signatures did not match: want=%s, got=%s", hex.EncodeToString(sigbuf), hex.EncodeToString(m.data.sig())))
+		err = verificationError(VerificationSignatureMismatch, m, vi, errors.New("macaroon.verify: signatures did not match"))
+		return err
 	}
 	return nil
 }
 
-func (m *Macaroon) verifyCaveat(s *Scheme, stack Stack, cSig []byte, c *Caveat, v *verifyContext, vi int, discharged []byte) error {
+// signedBy reports whether the signature of the macaroon was derived from the key, without verifying its discharges.
+// Each HMAC is counted against the operations of the verifier. The sigbuf must be the size of a key.
+func (m *Macaroon) signedBy(vr *verifier, key []byte, sigbuf []byte) (bool, error) {
+	s := vr.s
+	if err := vr.spend(); err != nil {
+		return false, verificationError(VerificationLimitExceeded, m, 0, err)
+	}
+	if err := s.hmac.HMAC(key, sigbuf, m.ID()); err != nil {
+		return false, fmt.Errorf("error executing hmac: %w", err)
+	}
+	for cur := m.data.cursor(); cur.next(); {
+		if err := vr.spend(); err != nil {
+			return false, caveatVerificationError(VerificationLimitExceeded, m, 0, cur.index, cur.caveat.ID(), err)
+		}
+		if err := s.hmac.HMAC(sigbuf, sigbuf, cur.caveat.data()); err != nil {
+			return false, fmt.Errorf("error executing hmac: %w", err)
+		}
+	}
+	return hmac.Equal(m.data.sig(), sigbuf), nil
+}
+
+func (m *Macaroon) verifyCaveat(vr *verifier, cSig []byte, c *Caveat, ci int, vi int, discharged []byte, depth int) error {
+	s := vr.s
+	v := vr.trace
+	if err := vr.spend(); err != nil {
+		return caveatVerificationError(VerificationLimitExceeded, m, vi, ci, c.ID(), err)
+	}
 	if len(c.VID()) == 0 { // first party
 		vo := v.trace(vi, TraceOpHMAC, cSig, c.data())
 		err := s.hmac.HMAC(cSig, cSig, c.data())
@@ -192,24 +264,36 @@ func (m *Macaroon) verifyCaveat(s *Scheme, stack Stack, cSig []byte, c *Caveat,
 	_, err := s.decrypt(*cK, c.VID(), cSig)
 	vo.setResult(*cK)
 	if err != nil {
-		return validationError(m, fmt.Errorf("macaroon.Caveat: failed to decrypt third-party caveat verification key: %w", err))
+		return caveatVerificationError(VerificationDecryptFailed, m, vi, ci, c.ID(), fmt.Errorf("macaroon.Caveat: failed to decrypt third-party caveat verification key: %w", err))
 	}
-	discharges := stack.Discharges()
+	vr.observe(VerifyEvent{Kind: VerifyEventCaveatDecrypted, StackIndex: vi, CaveatIndex: ci, Depth: depth})
+	discharges := vr.stack.Discharges()
 	for i := range discharges {
 		if bytes.Equal(discharges[i].ID(), c.ID()) {
+			if depth >= len(discharges) { // the chain must reuse a discharge, so it may be a cycle.
+				return caveatVerificationError(VerificationDischargeCycle, m, vi, ci, c.ID(), fmt.Errorf("macaroon.Caveat: discharge chain is deeper than the number of discharges: %v", c.ID()))
+			}
+			if err = checkLimit("MaxDepth", vr.maxDepth, depth+1); err != nil {
+				return caveatVerificationError(VerificationLimitExceeded, m, vi, ci, c.ID(), err)
+			}
+			vo.setDischarge(i + 1)
 			if discharged[i] < 255 {
 				discharged[i]++
 			}
-			if err = discharges[i].verify(s, stack, *cK, *cK, v, i+1, discharged); err != nil {
+			vr.observe(VerifyEvent{Kind: VerifyEventDischargeMatched, StackIndex: vi, CaveatIndex: ci, Discharge: i + 1, Depth: depth})
+			if err = discharges[i].verify(vr, *cK, *cK, i+1, discharged, depth+1); err != nil {
 				return err
 			}
+			if err = vr.spend(); err != nil {
+				return caveatVerificationError(VerificationLimitExceeded, m, vi, ci, c.ID(), err)
+			}
 			vo := v.trace(vi, TraceOpHMAC, cSig, c.data())
 			err = s.hmac.HMAC(cSig, cSig, c.data())
 			vo.setResult(cSig)
 			return err
 		}
 	}
-	return validationError(m, fmt.Errorf("macaroon.Caveat: missing discharge for caveat: %v", c.ID()))
+	return caveatVerificationError(VerificationMissingDischarge, m, vi, ci, c.ID(), fmt.Errorf("macaroon.Caveat: missing discharge for caveat: %v", c.ID()))
 }
 
 func (m *Macaroon) bindForRequest(s *Scheme, tm *Macaroon) error {
@@ -11,12 +11,14 @@ func (m *Macaroon) String() string {
 	jm.Location = jsonByteString(m.Location())
 	jm.ID = m.ID()
 	jm.Sig = m.Signature()
-	jm.Caveat = make([]jsonCaveat, len(m.Caveats()))
-	for i, c := range m.Caveats() {
-		jm.Caveat[i] = jsonCaveat{
-			Location: jsonByteString(c.Location()),
-			VID:      c.VID(),
-			CID:      c.ID(),
+	if !m.IsZero() {
+		jm.Caveat = make([]jsonCaveat, m.data.caveatCount)
+		for cur := m.data.cursor(); cur.next(); {
+			jm.Caveat[cur.index] = jsonCaveat{
+				Location: jsonByteString(cur.caveat.Location()),
+				VID:      cur.caveat.VID(),
+				CID:      cur.caveat.ID(),
+			}
 		}
 	}
 	js, _ := jsonMarshalNoEscape(jm, true)
@@ -0,0 +1,69 @@
+package mack
+
+import "context"
+
+//go:generate go tool -modfile=tools.mod golang.org/x/tools/cmd/stringer -type=VerifyEventKind -linecomment -output observer_string.go
+
+// VerifyEventKind identifies the kind of [VerifyEvent].
+type VerifyEventKind int
+
+const (
+	VerifyEventUnknown          = VerifyEventKind(iota) // Unknown
+	VerifyEventStart                                    // Start
+	VerifyEventMacaroonStart                            // MacaroonStart
+	VerifyEventCaveatDecrypted                          // CaveatDecrypted
+	VerifyEventDischargeMatched                         // DischargeMatched
+	VerifyEventBind                                     // Bind
+	VerifyEventMacaroonEnd                              // MacaroonEnd
+	VerifyEventFailure                                  // Failure
+	VerifyEventEnd                                      // End
+)
+
+// VerifyEvent is a lightweight event emitted to a [VerifyObserver] during [Scheme.Verify].
+// Unlike [TraceOp], it never contains keys or signatures, so it is safe to use in production.
+//
+// Events are emitted in order: a single Start, then a MacaroonStart and MacaroonEnd around each macaroon verified,
+// which may nest when a discharge is matched. If verification fails, a single Failure is emitted before the final End.
+type VerifyEvent struct {
+	Kind VerifyEventKind
+	// StackIndex is the position in the stack of the macaroon the event relates to. The target is 0.
+	StackIndex int
+	// CaveatIndex is the index of the caveat within the macaroon, or -1 if the event does not relate to a caveat.
+	CaveatIndex int
+	// Discharge is the stack index of the discharge macaroon matched by a DischargeMatched event.
+	Discharge int
+	// Depth is the nesting depth of the macaroon in the discharge chain. The target is 0.
+	Depth int
+	// Err is the error that caused verification to fail.
+	// It is only set on MacaroonEnd, Failure and End events.
+	Err error
+}
+
+// VerifyObserver receives events during [Scheme.Verify]. It is attached to a context with [WithVerifyObserver].
+// Observers are called synchronously, and should return quickly. They may be called by concurrent verifications.
+type VerifyObserver interface {
+	ObserveVerify(ctx context.Context, ev VerifyEvent)
+}
+
+// VerifyObserverFunc is an adapter to allow the use of ordinary functions as a [VerifyObserver].
+type VerifyObserverFunc func(ctx context.Context, ev VerifyEvent)
+
+// ObserveVerify calls f(ctx, ev).
+func (f VerifyObserverFunc) ObserveVerify(ctx context.Context, ev VerifyEvent) {
+	f(ctx, ev)
+}
+
+// WithVerifyObserver creates a new context with the observer attached.
+// [Scheme.Verify] will send events to the observer when called with this context.
+func WithVerifyObserver(ctx context.Context, obs VerifyObserver) context.Context {
+	return context.WithValue(ctx, verifyObserverKey{}, obs)
+}
+
+type verifyObserverKey struct{}
+
+func getVerifyObserver(ctx context.Context) VerifyObserver {
+	if obs, ok := ctx.Value(verifyObserverKey{}).(VerifyObserver); ok {
+		return obs
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+type observedEvent struct {
+	kind      mack.VerifyEventKind
+	stack     int
+	caveat    int
+	discharge int
+	depth     int
+	failed    bool
+}
+
+func recordEvents(events *[]observedEvent) mack.VerifyObserver {
+	return mack.VerifyObserverFunc(func(_ context.Context, ev mack.VerifyEvent) {
+		*events = append(*events, observedEvent{
+			kind:      ev.Kind,
+			stack:     ev.StackIndex,
+			caveat:    ev.CaveatIndex,
+			discharge: ev.Discharge,
+			depth:     ev.Depth,
+			failed:    ev.Err != nil,
+		})
+	})
+}
+
+func TestWithVerifyObserver(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "b > 2"},
+				},
+			},
+		},
+	})
+	var events []observedEvent
+	ctx := mack.WithVerifyObserver(context.Background(), recordEvents(&events))
+	if _, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	expected := []observedEvent{
+		{kind: mack.VerifyEventStart, caveat: -1},
+		{kind: mack.VerifyEventMacaroonStart, caveat: -1},
+		{kind: mack.VerifyEventCaveatDecrypted, caveat: 1},
+		{kind: mack.VerifyEventDischargeMatched, caveat: 1, discharge: 1},
+		{kind: mack.VerifyEventMacaroonStart, stack: 1, caveat: -1, depth: 1},
+		{kind: mack.VerifyEventBind, stack: 1, caveat: -1, depth: 1},
+		{kind: mack.VerifyEventMacaroonEnd, stack: 1, caveat: -1, depth: 1},
+		{kind: mack.VerifyEventMacaroonEnd, caveat: -1},
+		{kind: mack.VerifyEventEnd, caveat: -1},
+	}
+	assertEvents(t, expected, events)
+}
+
+func TestWithVerifyObserver_failure(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	tests := []struct {
+		name     string
+		stack    mack.Stack
+		opts     []mack.VerifyOption
+		expected observedEvent
+	}{
+		{
+			name:     "unbound-discharge",
+			stack:    append(mack.Stack{*fx.Target}, fx.Discharge...),
+			expected: observedEvent{kind: mack.VerifyEventFailure, stack: 1, caveat: -1, failed: true},
+		},
+		{
+			name:     "missing-discharge",
+			stack:    mack.Stack{*fx.Target},
+			expected: observedEvent{kind: mack.VerifyEventFailure, stack: 0, caveat: 0, failed: true},
+		},
+		{
+			name:  "revoked",
+			stack: fx.Stack,
+			opts: []mack.VerifyOption{mack.WithRevoker(mack.RevokerFunc(func(_ context.Context, m *mack.Macaroon) (bool, error) {
+				return string(m.ID()) == "3p", nil
+			}))},
+			expected: observedEvent{kind: mack.VerifyEventFailure, stack: 1, caveat: -1, failed: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []observedEvent
+			var failure error
+			ctx := mack.WithVerifyObserver(context.Background(), mack.VerifyObserverFunc(func(ctx context.Context, ev mack.VerifyEvent) {
+				if ev.Kind == mack.VerifyEventFailure {
+					failure = ev.Err
+				}
+				recordEvents(&events).ObserveVerify(ctx, ev)
+			}))
+			_, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, tt.stack, tt.opts...)
+			if err == nil {
+				t.Fatalf("Verify: expected error")
+			}
+			if !errors.Is(failure, err) {
+				t.Fatalf("Failure event: expected error %v, got %v", err, failure)
+			}
+			if len(events) < 2 {
+				t.Fatalf("expected at least 2 events, got %d", len(events))
+			}
+			assertEvents(t, []observedEvent{
+				tt.expected,
+				{kind: mack.VerifyEventEnd, caveat: -1, failed: true},
+			}, events[len(events)-2:])
+		})
+	}
+}
+
+func assertEvents(tb testing.TB, expected []observedEvent, actual []observedEvent) {
+	tb.Helper()
+	if len(expected) != len(actual) {
+		tb.Fatalf("expected %d events, got %d: %+v", len(expected), len(actual), actual)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			tb.Errorf("event[%d]: want %+v, got %+v", i, expected[i], actual[i])
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package mack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PredicateCheckerFunc is an adapter to allow the use of an ordinary function as a [PredicateChecker].
+type PredicateCheckerFunc func(ctx context.Context, predicate []byte) (bool, error)
+
+// CheckPredicate calls f(ctx, predicate).
+func (f PredicateCheckerFunc) CheckPredicate(ctx context.Context, predicate []byte) (bool, error) {
+	return f(ctx, predicate)
+}
+
+// PredicateMux is a [PredicateChecker] which dispatches each predicate to the checker registered
+// for the longest matching prefix, ie: `time-before `, `org = ` or `declared `.
+// The full predicate, including the prefix, is passed to the selected checker.
+//
+// Predicates that do not match any prefix are handled by the fallback checker, if one was set with
+// [PredicateMux.HandleUnknown]. Otherwise, they fail closed: the predicate is reported as not satisfied.
+//
+// A PredicateMux satisfies both [PredicateChecker] and the identical thirdparty.PredicateChecker,
+// so it may be used to clear a [VerifiedStack] and to check tickets in a thirdparty.Discharger.
+// The zero value is an empty mux which is ready to use. It is safe for concurrent use.
+type PredicateMux struct {
+	mu       sync.RWMutex
+	entries  []predicateMuxEntry // sorted by descending prefix length
+	fallback PredicateChecker
+}
+
+type predicateMuxEntry struct {
+	prefix  []byte
+	checker PredicateChecker
+}
+
+// Handle registers the checker for predicates starting with the given prefix.
+// It returns an error if the prefix is empty, or if a checker is already registered for the prefix.
+func (m *PredicateMux) Handle(prefix string, checker PredicateChecker) error {
+	if prefix == "" {
+		return fmt.Errorf("%w: PredicateMux.Handle: empty prefix", ErrInvalidArgument)
+	}
+	if checker == nil {
+		return fmt.Errorf("%w: PredicateMux.Handle: nil checker for prefix '%s'", ErrInvalidArgument, prefix)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.entries {
+		if string(m.entries[i].prefix) == prefix {
+			return fmt.Errorf("%w: PredicateMux.Handle: multiple registrations for prefix '%s'", ErrInvalidArgument, prefix)
+		}
+	}
+	m.entries = append(m.entries, predicateMuxEntry{
+		prefix:  []byte(prefix),
+		checker: checker,
+	})
+	sort.SliceStable(m.entries, func(i, j int) bool {
+		return len(m.entries[i].prefix) > len(m.entries[j].prefix)
+	})
+	return nil
+}
+
+// HandleFunc registers the checker function for predicates starting with the given prefix.
+func (m *PredicateMux) HandleFunc(prefix string, checker func(ctx context.Context, predicate []byte) (bool, error)) error {
+	if checker == nil {
+		return m.Handle(prefix, nil)
+	}
+	return m.Handle(prefix, PredicateCheckerFunc(checker))
+}
+
+// HandleUnknown sets the checker used for predicates that match no registered prefix.
+// If checker is nil, the mux fails closed for unknown predicates, which is the default.
+func (m *PredicateMux) HandleUnknown(checker PredicateChecker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fallback = checker
+}
+
+// CheckPredicate dispatches the predicate to the checker registered for the longest matching prefix.
+func (m *PredicateMux) CheckPredicate(ctx context.Context, predicate []byte) (bool, error) {
+	checker := m.Checker(predicate)
+	if checker == nil {
+		return false, nil
+	}
+	return checker.CheckPredicate(ctx, predicate)
+}
+
+// Checker returns the checker that would handle the predicate, or nil if it would fail closed.
+func (m *PredicateMux) Checker(predicate []byte) PredicateChecker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range m.entries {
+		if bytes.HasPrefix(predicate, m.entries[i].prefix) {
+			return m.entries[i].checker
+		}
+	}
+	return m.fallback
+}
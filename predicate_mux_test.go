@@ -0,0 +1,102 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	macaroon "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestPredicateMux(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	record := func(name string, result bool) macaroon.PredicateCheckerFunc {
+		return func(_ context.Context, _ []byte) (bool, error) {
+			calls = append(calls, name)
+			return result, nil
+		}
+	}
+	var mux macaroon.PredicateMux
+	if err := mux.Handle("org = ", record("org", true)); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := mux.Handle("org = acme", record("org-acme", true)); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := mux.Handle("time-before ", record("time", false)); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := mux.Handle("org = ", record("dup", true)); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("Handle: expected ErrInvalidArgument for duplicate prefix, got %v", err)
+	}
+	if err := mux.Handle("", record("empty", true)); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("Handle: expected ErrInvalidArgument for empty prefix, got %v", err)
+	}
+	tests := []struct {
+		predicate string
+		expected  bool
+		call      string
+	}{
+		{predicate: "org = foo", expected: true, call: "org"},
+		{predicate: "org = acme", expected: true, call: "org-acme"},
+		{predicate: "time-before 2006-01-02T15:04:05Z", expected: false, call: "time"},
+		{predicate: "unknown", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.predicate, func(t *testing.T) {
+			calls = nil
+			ok, err := mux.CheckPredicate(ctx, []byte(tt.predicate))
+			if err != nil {
+				t.Fatalf("CheckPredicate: unexpected error: %v", err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("CheckPredicate(%q): want %v, got %v", tt.predicate, tt.expected, ok)
+			}
+			switch {
+			case tt.call == "" && len(calls) != 0:
+				t.Fatalf("expected no checker to be called, got %v", calls)
+			case tt.call != "" && (len(calls) != 1 || calls[0] != tt.call):
+				t.Fatalf("expected checker %s to be called, got %v", tt.call, calls)
+			}
+		})
+	}
+	t.Run("fallback", func(t *testing.T) {
+		calls = nil
+		mux.HandleUnknown(record("fallback", true))
+		defer mux.HandleUnknown(nil)
+		ok, err := mux.CheckPredicate(ctx, []byte("unknown"))
+		if err != nil {
+			t.Fatalf("CheckPredicate: unexpected error: %v", err)
+		}
+		if !ok || len(calls) != 1 || calls[0] != "fallback" {
+			t.Fatalf("expected fallback to be called and satisfied, got ok=%v, calls=%v", ok, calls)
+		}
+	})
+}
+
+func TestPredicateMux_Clear(t *testing.T) {
+	ctx := context.Background()
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: "org = acme"},
+			{ID: "user = foo"},
+		},
+	})
+	var mux macaroon.PredicateMux
+	_ = mux.HandleFunc("org = ", func(_ context.Context, p []byte) (bool, error) {
+		return string(p) == "org = acme", nil
+	})
+	vs := macaroon.InsecureVerifiedStack(fx.Stack)
+	if err := vs.Clear(ctx, &mux); !errors.Is(err, macaroon.ErrPredicateNotSatisfied) {
+		t.Fatalf("expected unknown predicate to fail closed, got %v", err)
+	}
+	_ = mux.HandleFunc("user = ", func(_ context.Context, p []byte) (bool, error) {
+		return string(p) == "user = foo", nil
+	})
+	if err := vs.Clear(ctx, &mux); err != nil {
+		t.Fatalf("Clear: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package mack
+
+import "bytes"
+
+// UndischargedCaveat is a third-party caveat for which [Stack.Prune] found no discharge macaroon,
+// or whose discharge macaroon was already selected for another caveat with the same ID.
+type UndischargedCaveat struct {
+	// StackIndex is the position in the pruned stack of the macaroon containing the caveat. The target is 0.
+	StackIndex int
+	// Caveat is the third-party caveat.
+	Caveat Caveat
+}
+
+// Prune returns a normalized copy of the stack, containing the target and only the discharge macaroons it needs.
+// Starting from the target, it walks the third-party caveats of each macaroon in order, selecting the first
+// discharge macaroon whose ID matches the caveat ID, as [Scheme.Verify] does, and then walking the caveats of that discharge.
+// If that discharge was already selected for another caveat, the caveat can not be discharged, since [Scheme.Verify]
+// rejects a discharge which is used more than once. Discharges which are duplicates, or which do not match any caveat, are dropped.
+// The discharges in the pruned stack are in the order they were selected, which is the order they are used by [Scheme.Verify].
+//
+// Prune does not verify any signatures, so it may be used by a client to repair a stack before sending it.
+// It returns the third-party caveats which remain undischarged, if any.
+func (s Stack) Prune() (Stack, []UndischargedCaveat) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	discharges := s.Discharges()
+	p := stackPruner{
+		discharges: discharges,
+		used:       make([]bool, len(discharges)),
+		pruned:     make(Stack, 1, len(s)),
+	}
+	p.pruned[0] = s[0]
+	p.walk(0)
+	return p.pruned, p.undischarged
+}
+
+type stackPruner struct {
+	discharges   []Macaroon
+	used         []bool
+	pruned       Stack
+	undischarged []UndischargedCaveat
+}
+
+// walk selects discharges for the third-party caveats of the macaroon at index i of the pruned stack.
+func (p *stackPruner) walk(i int) {
+	m := &p.pruned[i]
+	for cur := m.data.cursor(); cur.next(); {
+		if !cur.caveat.thirdParty() {
+			continue
+		}
+		d := p.match(cur.caveat.ID())
+		if d < 0 || p.used[d] {
+			p.undischarged = append(p.undischarged, UndischargedCaveat{StackIndex: i, Caveat: cur.caveat})
+			continue
+		}
+		p.used[d] = true
+		p.pruned = append(p.pruned, p.discharges[d])
+		p.walk(len(p.pruned) - 1)
+	}
+}
+
+// match returns the index of the first discharge with the id, or -1, like [matchDischarge].
+func (p *stackPruner) match(id []byte) int {
+	for i := range p.discharges {
+		if bytes.Equal(p.discharges[i].ID(), id) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,148 @@
+package mack_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestStack_Prune(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{
+				ID:         "3p-a",
+				ThirdParty: "https://a.example.org",
+				Caveats: []testhelpers.Caveat{
+					{
+						ID:         "3p-a-nested",
+						ThirdParty: "https://nested.example.org",
+					},
+				},
+			},
+			{ID: "a > 1"},
+			{
+				ID:         "3p-b",
+				ThirdParty: "https://b.example.org",
+			},
+		},
+	})
+	ids := func(st mack.Stack) []string {
+		out := make([]string, len(st))
+		for i := range st {
+			out[i] = string(st[i].ID())
+		}
+		return out
+	}
+	expected := []string{"target", "3p-a", "3p-a-nested", "3p-b"}
+	if got := ids(fx.Stack); !equalStrings(got, expected) {
+		t.Fatalf("unexpected fixture order: %v", got)
+	}
+	unrelated, err := fx.Scheme.NewMacaroon("loc", []byte("unrelated"), testhelpers.ThirdPartyKey, []byte("x = 1"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	tests := []struct {
+		name         string
+		stack        mack.Stack
+		expected     []string
+		undischarged []string
+	}{
+		{
+			name:     "normalized",
+			stack:    fx.Stack,
+			expected: expected,
+		},
+		{
+			name:     "shuffled",
+			stack:    mack.Stack{fx.Stack[0], fx.Stack[3], fx.Stack[2], fx.Stack[1]},
+			expected: expected,
+		},
+		{
+			name:     "duplicate-and-unrelated",
+			stack:    mack.Stack{fx.Stack[0], unrelated, fx.Stack[1], fx.Stack[1], fx.Stack[2], fx.Stack[3], fx.Stack[3]},
+			expected: expected,
+		},
+		{
+			name:         "missing",
+			stack:        mack.Stack{fx.Stack[0], fx.Stack[1], unrelated},
+			expected:     []string{"target", "3p-a"},
+			undischarged: []string{"3p-a-nested@3p-a", "3p-b@target"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pruned, undischarged := tt.stack.Prune()
+			if got := ids(pruned); !equalStrings(got, tt.expected) {
+				t.Fatalf("Prune: want %v, got %v", tt.expected, got)
+			}
+			var missing []string
+			for _, u := range undischarged {
+				missing = append(missing, string(u.Caveat.ID())+"@"+string(pruned[u.StackIndex].ID()))
+			}
+			if !equalStrings(missing, tt.undischarged) {
+				t.Fatalf("Prune: undischarged want %v, got %v", tt.undischarged, missing)
+			}
+			if len(undischarged) > 0 {
+				return
+			}
+			if _, err := fx.Scheme.Verify(context.Background(), testhelpers.RootKey, pruned); err != nil {
+				t.Fatalf("Verify: pruned stack did not verify: %v", err)
+			}
+		})
+	}
+	if pruned, undischarged := mack.Stack(nil).Prune(); pruned != nil || undischarged != nil {
+		t.Fatalf("Prune: expected an empty stack to prune to nil")
+	}
+}
+
+func TestStack_Prune_repeatedCaveatID(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{
+				ID:         "3p",
+				ThirdParty: "https://a.example.org",
+			},
+			{
+				ID:         "3p",
+				ThirdParty: "https://b.example.org",
+			},
+		},
+	})
+	if len(fx.Stack) != 3 {
+		t.Fatalf("expected a discharge for each caveat, got %d macaroons", len(fx.Stack))
+	}
+	ctx := context.Background()
+	if _, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack); err == nil {
+		t.Fatalf("Verify: expected repeated caveat IDs to fail verification")
+	}
+	pruned, undischarged := fx.Stack.Prune()
+	if len(pruned) != 2 || string(pruned[1].ID()) != "3p" {
+		t.Fatalf("Prune: expected the target and the first discharge, got %d macaroons", len(pruned))
+	}
+	if len(undischarged) != 1 || undischarged[0].StackIndex != 0 || string(undischarged[0].Caveat.ID()) != "3p" {
+		t.Fatalf("Prune: expected the second caveat to be undischarged, got %v", undischarged)
+	}
+	// both caveats are bound to the first discharge, as Prune selected it.
+	if g := pruned.Graph(); len(g.Reused) != 1 || g.Reused[0] != 1 {
+		t.Fatalf("Graph: expected discharge 1 to be reused, got %v", g.Reused)
+	}
+	if _, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, pruned); err == nil {
+		t.Fatalf("Verify: expected the pruned stack to fail verification")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
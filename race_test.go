@@ -0,0 +1,103 @@
+package mack_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+// TestStack_concurrent shares a stack between goroutines which verify and clear it at the same time.
+// It is intended to be run with the race detector.
+func TestStack_concurrent(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "org = acme"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "user = alice"},
+				},
+			},
+		},
+	})
+	pcheck := mack.PredicateCheckerFunc(func(context.Context, []byte) (bool, error) {
+		return true, nil
+	})
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		fn   func(stack mack.Stack) error
+	}{
+		{
+			name: "Verify",
+			fn: func(stack mack.Stack) error {
+				_, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, stack)
+				return err
+			},
+		},
+		{
+			name: "Clear",
+			fn: func(stack mack.Stack) error {
+				return mack.InsecureVerifiedStack(stack).Clear(ctx, pcheck)
+			},
+		},
+		{
+			name: "ClearAll",
+			fn: func(stack mack.Stack) error {
+				_, err := mack.InsecureVerifiedStack(stack).ClearAll(ctx, pcheck)
+				return err
+			},
+		},
+		{
+			name: "Predicates",
+			fn: func(stack mack.Stack) error {
+				_ = mack.InsecureVerifiedStack(stack).Predicates()
+				return nil
+			},
+		},
+		{
+			name: "Caveats",
+			fn: func(stack mack.Stack) error {
+				for i := range stack {
+					_ = stack[i].Caveats()
+				}
+				return nil
+			},
+		},
+	}
+	const rounds = 20
+	const goroutines = 8
+	for round := 0; round < rounds; round++ {
+		// use fresh copies each round, so that nothing is read from the stack before the goroutines start.
+		stack := make(mack.Stack, len(fx.Stack))
+		for i := range fx.Stack {
+			stack[i] = mack.Clone(&fx.Stack[i])
+		}
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs := make(chan error, goroutines*len(tests))
+		for g := 0; g < goroutines; g++ {
+			for _, tt := range tests {
+				wg.Add(1)
+				go func(fn func(stack mack.Stack) error) {
+					defer wg.Done()
+					<-start
+					if err := fn(stack); err != nil {
+						errs <- err
+					}
+				}(tt.fn)
+			}
+		}
+		close(start)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("round %d: unexpected error: %v", round, err)
+		}
+	}
+}
@@ -22,9 +22,13 @@ func (c *RawCaveat) size() int {
 // NewFromRaw creates a new Macaroon with the given Raw macaroon data.
 // The raw macaroon data consists of the ID, location, caveats, and signature.
 // The function copies the raw macaroon data into a new Macaroon instance.
-// There is no validation done on the resulting Macaroon; this is only useful for Decoding a macaroon from a wire format.
-func NewFromRaw(raw Raw) Macaroon {
-	nmd := newMacaroonData(raw.Location, raw.ID, len(raw.Signature), raw.Caveats...)
+// The resulting Macaroon is not verified; this is only useful for Decoding a macaroon from a wire format.
+// If any field is larger than [MaxFieldSize], the error wraps ErrInvalidArgument.
+func NewFromRaw(raw Raw) (Macaroon, error) {
+	nmd, err := newMacaroonData(raw.Location, raw.ID, len(raw.Signature), raw.Caveats...)
+	if err != nil {
+		return Macaroon{}, err
+	}
 	copy(nmd.sig(), raw.Signature)
-	return Macaroon{data: nmd}
+	return fromData(nmd), nil
 }
@@ -2,6 +2,7 @@ package mack
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -24,7 +25,10 @@ func TestNewFromRaw(t *testing.T) {
 		},
 		Signature: []byte(`sig123`),
 	}
-	m := NewFromRaw(raw)
+	m, err := NewFromRaw(raw)
+	if err != nil {
+		t.Fatalf("NewFromRaw: %v", err)
+	}
 	if !bytes.Equal(raw.ID, m.ID()) {
 		t.Errorf("id mismatch: got %v want %v", m.ID(), raw.ID)
 	}
@@ -51,3 +55,48 @@ func TestNewFromRaw(t *testing.T) {
 		}
 	}
 }
+
+func TestNewFromRaw_fieldSize(t *testing.T) {
+	if MaxFieldSize > 1<<20 {
+		t.Skip("MaxFieldSize is too large to test")
+	}
+	large := bytes.Repeat([]byte{'a'}, MaxFieldSize)
+	tests := []struct {
+		name string
+		raw  Raw
+	}{
+		{name: "id", raw: Raw{ID: append(large, 'a')}},
+		{name: "location", raw: Raw{ID: []byte(`id`), Location: string(large) + "a"}},
+		{name: "signature", raw: Raw{ID: []byte(`id`), Signature: append(large, 'a')}},
+		{name: "cid", raw: Raw{ID: []byte(`id`), Caveats: []RawCaveat{{CID: append(large, 'a')}}}},
+		{name: "vid", raw: Raw{ID: []byte(`id`), Caveats: []RawCaveat{{CID: []byte(`cid`), VID: append(large, 'a')}}}},
+		{name: "caveats", raw: Raw{ID: []byte(`id`), Caveats: make([]RawCaveat, MaxFieldSize+1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFromRaw(tt.raw); !errors.Is(err, ErrInvalidArgument) {
+				t.Fatalf("NewFromRaw: expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+	t.Run("max", func(t *testing.T) {
+		// the sizes of each field are at the limit, so their sum overflows the field size type.
+		raw := Raw{
+			ID:        large,
+			Location:  string(large),
+			Caveats:   []RawCaveat{{CID: large, VID: large, Location: string(large)}, {CID: []byte(`cid`)}},
+			Signature: []byte(`sig`),
+		}
+		m, err := NewFromRaw(raw)
+		if err != nil {
+			t.Fatalf("NewFromRaw: %v", err)
+		}
+		if !bytes.Equal(m.ID(), raw.ID) || m.Location() != raw.Location || !bytes.Equal(m.Signature(), raw.Signature) {
+			t.Fatalf("NewFromRaw: fields do not match")
+		}
+		cs := m.Caveats()
+		if len(cs) != 2 || !bytes.Equal(cs[0].VID(), large) || cs[0].Location() != raw.Caveats[0].Location || !bytes.Equal(cs[1].ID(), []byte(`cid`)) {
+			t.Fatalf("NewFromRaw: caveats do not match")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package revocation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/justenwalker/mack"
+)
+
+type stringError string
+
+func (e stringError) Error() string {
+	return string(e)
+}
+
+// ErrInvalidFile is returned when a revocation file is malformed.
+const ErrInvalidFile = stringError("revocation: invalid file")
+
+// fileMagic identifies a revocation file, and the version of its format.
+const fileMagic = "MACKRVL1"
+
+// FileList is a [mack.Revoker] which reads its revocations from a file written by [WriteFile].
+//
+// The file holds a sorted set of SHA-256 digests of each entry, so every revocation occupies 32 bytes
+// regardless of the size of the revoked value, and lookups are a binary search.
+// The digests are collision resistant, so unlike a bloom filter, no lookup yields a false positive.
+type FileList struct {
+	path string
+
+	mu      sync.RWMutex
+	digests []byte
+}
+
+// OpenFile loads the revocation file at path.
+func OpenFile(path string) (*FileList, error) {
+	l := &FileList{path: path}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload re-reads the revocation file, so that revocations written since it was opened take effect.
+// If the file cannot be loaded, the previously loaded revocations are kept.
+func (l *FileList) Reload() error {
+	bs, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("revocation: failed to read file: %w", err)
+	}
+	digests, ok := bytes.CutPrefix(bs, []byte(fileMagic))
+	if !ok || len(digests)%sha256.Size != 0 {
+		return fmt.Errorf("%w: '%s'", ErrInvalidFile, l.path)
+	}
+	for i := sha256.Size; i < len(digests); i += sha256.Size {
+		if bytes.Compare(digests[i-sha256.Size:i], digests[i:i+sha256.Size]) >= 0 {
+			return fmt.Errorf("%w: '%s': digests are not sorted", ErrInvalidFile, l.path)
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.digests = digests
+	return nil
+}
+
+// Len returns the number of revocations in the list.
+func (l *FileList) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.digests) / sha256.Size
+}
+
+// Contains returns true if the entry is in the list.
+func (l *FileList) Contains(e Entry) bool {
+	d := e.digest()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	n := len(l.digests) / sha256.Size
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(l.digests[i*sha256.Size:(i+1)*sha256.Size], d[:]) >= 0
+	})
+	return i < n && bytes.Equal(l.digests[i*sha256.Size:(i+1)*sha256.Size], d[:])
+}
+
+// IsRevoked returns true if the macaroon's ID, signature, or any of its revocation-id caveats are in the list.
+func (l *FileList) IsRevoked(_ context.Context, m *mack.Macaroon) (bool, error) {
+	return matches(m, l.Contains), nil
+}
+
+func (e Entry) digest() [sha256.Size]byte {
+	return sha256.Sum256([]byte(e.key()))
+}
+
+// WriteFile atomically writes the entries to a revocation file at path, replacing it if it exists.
+func WriteFile(path string, entries []Entry) error {
+	digests := make([][sha256.Size]byte, len(entries))
+	for i := range entries {
+		digests[i] = entries[i].digest()
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return bytes.Compare(digests[i][:], digests[j][:]) < 0
+	})
+	bs := make([]byte, 0, len(fileMagic)+len(digests)*sha256.Size)
+	bs = append(bs, fileMagic...)
+	for i := range digests {
+		if i > 0 && digests[i] == digests[i-1] {
+			continue
+		}
+		bs = append(bs, digests[i][:]...)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("revocation: failed to create file: %w", err)
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck // no longer exists after a successful rename
+	if err = f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("revocation: failed to create file: %w", err)
+	}
+	if _, err = f.Write(bs); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("revocation: failed to write file: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("revocation: failed to write file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("revocation: failed to write file: %w", err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("revocation: failed to replace file: %w", err)
+	}
+	return nil
+}
+
+var _ mack.Revoker = (*FileList)(nil)
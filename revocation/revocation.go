@@ -0,0 +1,165 @@
+// Package revocation provides implementations of [mack.Revoker], which allow macaroons to be revoked
+// without rotating the root key that minted them.
+//
+// A macaroon may be revoked by one of three properties:
+//
+//   - its ID, which revokes it and every macaroon attenuated from it.
+//   - its signature, which revokes only that exact macaroon.
+//   - a revocation-id caveat, which revokes every macaroon carrying the caveat.
+//     This allows a family of macaroons, ie: those issued to the same session, to be revoked at once.
+//
+// A [List] holds revocations in memory, and a [FileList] reads a compact, sorted file of revocations
+// written by [WriteFile]. Either is passed to [mack.Scheme.Verify] with [mack.WithRevoker].
+package revocation
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/justenwalker/mack"
+)
+
+// PrefixRevocationID is the prefix of a revocation-id caveat.
+const PrefixRevocationID = "revocation-id "
+
+// Caveat creates a revocation-id caveat.
+func Caveat(id []byte) []byte {
+	return append([]byte(PrefixRevocationID), id...)
+}
+
+// AddCaveat appends a revocation-id caveat to the macaroon, returning a new Macaroon.
+func AddCaveat(s *mack.Scheme, m *mack.Macaroon, id []byte) (mack.Macaroon, error) {
+	return s.AddFirstPartyCaveat(m, Caveat(id))
+}
+
+// RevocationIDs returns the ids of all revocation-id caveats in the macaroon.
+func RevocationIDs(m *mack.Macaroon) [][]byte {
+	var ids [][]byte
+	for _, c := range m.FirstPartyCaveats() {
+		if id, ok := bytes.CutPrefix(c.ID(), []byte(PrefixRevocationID)); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Register registers a checker with the mux which satisfies every revocation-id caveat.
+// The caveat carries no condition of its own; it is enforced by the [mack.Revoker] during verification.
+func Register(mux *mack.PredicateMux) error {
+	return mux.HandleFunc(PrefixRevocationID, func(context.Context, []byte) (bool, error) {
+		return true, nil
+	})
+}
+
+//go:generate go tool -modfile=../tools.mod golang.org/x/tools/cmd/stringer -type=Kind -linecomment -output revocation_string.go
+
+// Kind is the property of a macaroon that a revocation matches.
+type Kind byte
+
+const (
+	KindID           = Kind(1) // id
+	KindSignature    = Kind(2) // signature
+	KindRevocationID = Kind(3) // revocation-id
+)
+
+// Entry is a single revocation.
+type Entry struct {
+	Kind  Kind
+	Value []byte
+}
+
+// ByID revokes the macaroon with the given ID, and every macaroon attenuated from it.
+func ByID(id []byte) Entry {
+	return Entry{Kind: KindID, Value: id}
+}
+
+// BySignature revokes the macaroon with the given signature.
+func BySignature(sig []byte) Entry {
+	return Entry{Kind: KindSignature, Value: sig}
+}
+
+// ByRevocationID revokes every macaroon with a revocation-id caveat for the given id.
+func ByRevocationID(id []byte) Entry {
+	return Entry{Kind: KindRevocationID, Value: id}
+}
+
+func (e Entry) key() string {
+	return string(append([]byte{byte(e.Kind)}, e.Value...))
+}
+
+// matches returns true if contains reports any of the properties of the macaroon as revoked.
+func matches(m *mack.Macaroon, contains func(e Entry) bool) bool {
+	if contains(ByID(m.ID())) || contains(BySignature(m.Signature())) {
+		return true
+	}
+	for _, id := range RevocationIDs(m) {
+		if contains(ByRevocationID(id)) {
+			return true
+		}
+	}
+	return false
+}
+
+// List is an in-memory set of revocations.
+// The zero value is an empty list which is ready to use. It is safe for concurrent use.
+type List struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// Revoke adds the entries to the list.
+func (l *List) Revoke(entries ...Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = make(map[string]Entry, len(entries))
+	}
+	for _, e := range entries {
+		e.Value = bytes.Clone(e.Value)
+		l.entries[e.key()] = e
+	}
+}
+
+// Remove removes the entries from the list.
+func (l *List) Remove(entries ...Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range entries {
+		delete(l.entries, e.key())
+	}
+}
+
+// Contains returns true if the entry is in the list.
+func (l *List) Contains(e Entry) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.entries[e.key()]
+	return ok
+}
+
+// Entries returns a copy of the entries in the list, sorted by kind and value.
+// The result may be persisted with [WriteFile].
+func (l *List) Entries() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, Entry{Kind: e.Kind, Value: bytes.Clone(e.Value)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return bytes.Compare(entries[i].Value, entries[j].Value) < 0
+	})
+	return entries
+}
+
+// IsRevoked returns true if the macaroon's ID, signature, or any of its revocation-id caveats are in the list.
+func (l *List) IsRevoked(_ context.Context, m *mack.Macaroon) (bool, error) {
+	return matches(m, l.Contains), nil
+}
+
+var _ mack.Revoker = (*List)(nil)
@@ -0,0 +1,168 @@
+package revocation_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+	"github.com/justenwalker/mack/revocation"
+)
+
+type revoker interface {
+	mack.Revoker
+	Contains(e revocation.Entry) bool
+}
+
+func newFixture(t *testing.T) testhelpers.Fixture {
+	t.Helper()
+	return testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: string(revocation.Caveat([]byte("session-1")))},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "org = acme"},
+				},
+			},
+		},
+	})
+}
+
+func TestRevokers(t *testing.T) {
+	fx := newFixture(t)
+	tests := []struct {
+		name    string
+		entry   revocation.Entry
+		revoked bool
+	}{
+		{name: "none", entry: revocation.ByID([]byte("other")), revoked: false},
+		{name: "target-id", entry: revocation.ByID(fx.Stack.Target().ID()), revoked: true},
+		{name: "discharge-id", entry: revocation.ByID([]byte("3p")), revoked: true},
+		{name: "signature", entry: revocation.BySignature(fx.Stack.Target().Signature()), revoked: true},
+		{name: "revocation-id", entry: revocation.ByRevocationID([]byte("session-1")), revoked: true},
+		{name: "kind-mismatch", entry: revocation.ByRevocationID(fx.Stack.Target().ID()), revoked: false},
+	}
+	lists := map[string]func(t *testing.T, e revocation.Entry) revoker{
+		"memory": func(_ *testing.T, e revocation.Entry) revoker {
+			var l revocation.List
+			l.Revoke(e)
+			return &l
+		},
+		"file": func(t *testing.T, e revocation.Entry) revoker {
+			path := filepath.Join(t.TempDir(), "revoked")
+			if err := revocation.WriteFile(path, []revocation.Entry{revocation.ByID([]byte("a")), e, revocation.ByID([]byte("z"))}); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			l, err := revocation.OpenFile(path)
+			if err != nil {
+				t.Fatalf("OpenFile: %v", err)
+			}
+			return l
+		},
+	}
+	ctx := context.Background()
+	for lname, newList := range lists {
+		for _, tt := range tests {
+			t.Run(lname+"/"+tt.name, func(t *testing.T) {
+				l := newList(t, tt.entry)
+				if !l.Contains(tt.entry) {
+					t.Fatalf("Contains(%v): expected true", tt.entry)
+				}
+				_, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithRevoker(l))
+				if !tt.revoked {
+					if err != nil {
+						t.Fatalf("Verify: unexpected error: %v", err)
+					}
+					return
+				}
+				if !errors.Is(err, mack.ErrRevoked) {
+					t.Fatalf("Verify: expected ErrRevoked, got %v", err)
+				}
+				if !errors.Is(err, mack.ErrVerificationFailed) {
+					t.Fatalf("Verify: expected ErrVerificationFailed, got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestList_Remove(t *testing.T) {
+	var l revocation.List
+	a, b := revocation.ByID([]byte("a")), revocation.BySignature([]byte("b"))
+	l.Revoke(a, b)
+	l.Remove(a)
+	if l.Contains(a) || !l.Contains(b) {
+		t.Fatalf("Remove: unexpected contents %v", l.Entries())
+	}
+}
+
+func TestFileList_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked")
+	var l revocation.List
+	l.Revoke(revocation.ByID([]byte("a")), revocation.ByID([]byte("a")), revocation.ByRevocationID([]byte("b")))
+	if err := revocation.WriteFile(path, l.Entries()); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fl, err := revocation.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if fl.Len() != 2 {
+		t.Fatalf("Len: want 2, got %d", fl.Len())
+	}
+	c := revocation.ByID([]byte("c"))
+	l.Revoke(c)
+	if err = revocation.WriteFile(path, l.Entries()); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if fl.Contains(c) {
+		t.Fatalf("Contains: expected false before reload")
+	}
+	if err = fl.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !fl.Contains(c) {
+		t.Fatalf("Contains: expected true after reload")
+	}
+}
+
+func TestOpenFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked")
+	if err := revocation.WriteFile(path, nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := revocation.OpenFile(path + ".missing"); err == nil {
+		t.Fatalf("OpenFile: expected error for missing file")
+	}
+	if err := os.WriteFile(path, []byte("not a revocation file"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := revocation.OpenFile(path); !errors.Is(err, revocation.ErrInvalidFile) {
+		t.Fatalf("OpenFile: expected ErrInvalidFile, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	fx := newFixture(t)
+	var mux mack.PredicateMux
+	_ = mux.HandleFunc("org = ", func(context.Context, []byte) (bool, error) {
+		return true, nil
+	})
+	if err := revocation.Register(&mux); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	ctx := context.Background()
+	vs, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithRevoker(&revocation.List{}))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err = vs.Clear(ctx, &mux); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+}
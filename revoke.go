@@ -0,0 +1,54 @@
+package mack
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithRevoker consults the [Revoker] for every macaroon in the stack, once its signatures have been verified.
+// If any macaroon is revoked, verification fails with an error satisfying both
+// errors.Is(err, ErrVerificationFailed) and errors.Is(err, ErrRevoked).
+func WithRevoker(r Revoker) VerifyOption {
+	return func(o *verifyOptions) {
+		o.revoker = r
+	}
+}
+
+// Revoker decides if a macaroon has been revoked.
+//
+// Revocation is checked against each macaroon as it appears in the stack. Adding a caveat changes the signature
+// of a macaroon, so revoking by signature affects only that exact macaroon, while revoking by macaroon ID
+// also revokes every macaroon attenuated from it.
+type Revoker interface {
+	// IsRevoked returns true if the macaroon has been revoked.
+	// An error indicates the revocation status could not be determined, in which case verification fails.
+	IsRevoked(ctx context.Context, m *Macaroon) (bool, error)
+}
+
+// RevokerFunc is an adapter to allow the use of an ordinary function as a [Revoker].
+type RevokerFunc func(ctx context.Context, m *Macaroon) (bool, error)
+
+// IsRevoked calls f(ctx, m).
+func (f RevokerFunc) IsRevoked(ctx context.Context, m *Macaroon) (bool, error) {
+	return f(ctx, m)
+}
+
+func (vr *verifier) checkRevoked(r Revoker) error {
+	for i := range vr.stack {
+		m := &vr.stack[i]
+		revoked, err := r.IsRevoked(vr.ctx, m)
+		if err != nil {
+			err = fmt.Errorf("macaroon: failed to check revocation of macaroon %d: %w", i, err)
+			vr.trace.fail(i, err)
+			vr.failedAt(i, -1)
+			return err
+		}
+		if revoked {
+			err = verificationError(VerificationRevoked, m, i, fmt.Errorf("%w: macaroon %d", ErrRevoked, i))
+			vr.trace.fail(i, err)
+			vr.failedAt(i, -1)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package mack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestScheme_Verify_WithRevoker(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	ctx := context.Background()
+	var checked [][]byte
+	revokeID := func(id string) mack.Revoker {
+		return mack.RevokerFunc(func(_ context.Context, m *mack.Macaroon) (bool, error) {
+			checked = append(checked, m.ID())
+			return string(m.ID()) == id, nil
+		})
+	}
+	_, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithRevoker(revokeID("none")))
+	if err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	if len(checked) != len(fx.Stack) {
+		t.Fatalf("Verify: expected every macaroon to be checked, got %d", len(checked))
+	}
+	_, err = fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithRevoker(revokeID("3p")))
+	if !errors.Is(err, mack.ErrRevoked) || !errors.Is(err, mack.ErrVerificationFailed) {
+		t.Fatalf("Verify: expected ErrRevoked and ErrVerificationFailed, got %v", err)
+	}
+	var ve interface{ Macaroon() *mack.Macaroon }
+	if !errors.As(err, &ve) || string(ve.Macaroon().ID()) != "3p" {
+		t.Fatalf("Verify: expected error for the revoked discharge, got %v", err)
+	}
+	errUnavailable := errors.New("unavailable")
+	_, err = fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithRevoker(mack.RevokerFunc(func(context.Context, *mack.Macaroon) (bool, error) {
+		return false, errUnavailable
+	})))
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("Verify: expected revoker error, got %v", err)
+	}
+}
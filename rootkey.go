@@ -0,0 +1,49 @@
+package mack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RootKeyStore stores the root keys used to mint and verify macaroons.
+//
+// The store is responsible for associating a macaroon ID with its root key.
+// Implementations typically embed an identifier for the root key in each macaroon ID they issue,
+// so that many macaroons can share a root key while each having a unique ID.
+type RootKeyStore interface {
+	// RootKey returns a new, unique macaroon ID and the root key that should be used to mint a macaroon with it.
+	RootKey(ctx context.Context) (id []byte, key []byte, err error)
+
+	// Get returns the root key for the given macaroon ID.
+	// If there is no such key, or it has expired, the error should wrap ErrRootKeyNotFound.
+	Get(ctx context.Context, id []byte) ([]byte, error)
+}
+
+// NewMacaroonWithStore creates a new Macaroon like [Scheme.NewMacaroon], using the ID and root key provided by the store.
+func (s *Scheme) NewMacaroonWithStore(ctx context.Context, store RootKeyStore, loc string, caveats ...[]byte) (Macaroon, error) {
+	id, key, err := store.RootKey(ctx)
+	if err != nil {
+		return Macaroon{}, fmt.Errorf("macaroon: failed to get root key: %w", err)
+	}
+	return s.NewMacaroon(loc, id, key, caveats...)
+}
+
+// VerifyWithStore verifies the stack like [Scheme.Verify], resolving the root key from the store
+// using the ID of the target macaroon.
+// If the store has no key for the ID, the error satisfies both errors.Is(err, ErrVerificationFailed)
+// and errors.Is(err, ErrRootKeyNotFound).
+func (s *Scheme) VerifyWithStore(ctx context.Context, store RootKeyStore, stack Stack, opts ...VerifyOption) (VerifiedStack, error) {
+	if len(stack) == 0 {
+		return VerifiedStack{}, fmt.Errorf("%w: empty stack", ErrInvalidArgument)
+	}
+	target := stack.Target()
+	key, err := store.Get(ctx, target.ID())
+	if errors.Is(err, ErrRootKeyNotFound) {
+		return VerifiedStack{}, verificationError(VerificationRootKeyNotFound, target, 0, err)
+	}
+	if err != nil {
+		return VerifiedStack{}, fmt.Errorf("macaroon: failed to get root key: %w", err)
+	}
+	return s.Verify(ctx, key, stack, opts...)
+}
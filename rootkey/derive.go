@@ -0,0 +1,127 @@
+package rootkey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/justenwalker/mack"
+)
+
+const versionSize = 4
+
+// DeriverConfig configures a [Deriver].
+type DeriverConfig struct {
+	// Scheme is the cryptographic scheme used for Macaroons (Required).
+	// Its HMACScheme is used for key derivation.
+	Scheme *mack.Scheme
+	// Masters maps a version to a master secret (Required).
+	// Macaroons minted with a version that is removed from this map no longer verify.
+	Masters map[uint32][]byte
+	// Version is the version of the master secret used to mint new macaroons.
+	// It must be present in Masters.
+	Version uint32
+	// Label is an optional context label mixed into every derived key,
+	// so the same master secret can be used for several independent purposes.
+	Label string
+	// Rand is the source of random bytes for macaroon ID nonces. If nil, [crypto/rand.Read] is used.
+	Rand func([]byte) (int, error)
+}
+
+// Deriver is a [mack.RootKeyStore] that derives root keys deterministically from a master secret,
+// so that no per-macaroon key needs to be stored.
+//
+// Each macaroon ID is a 4-byte big-endian master secret version followed by a 16-byte random nonce.
+// The root key is derived with [mack.Scheme.DeriveKey] from the master secret for that version,
+// with the context label and the macaroon ID as the HKDF info.
+// Rotating the master secret is done by adding a new version, and making it the current version;
+// macaroons minted with older versions continue to verify until their version is removed.
+type Deriver struct {
+	scheme   *mack.Scheme
+	masters  map[uint32][]byte
+	version  uint32
+	label    []byte
+	readFunc func([]byte) (int, error)
+}
+
+// NewDeriver creates a new Deriver.
+func NewDeriver(cfg DeriverConfig) (*Deriver, error) {
+	if cfg.Scheme == nil {
+		return nil, errors.New("cfg.Scheme is nil")
+	}
+	if _, ok := cfg.Masters[cfg.Version]; !ok {
+		return nil, fmt.Errorf("cfg.Masters does not contain the current version %d", cfg.Version)
+	}
+	masters := make(map[uint32][]byte, len(cfg.Masters))
+	for v, secret := range cfg.Masters {
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("cfg.Masters: empty master secret for version %d", v)
+		}
+		masters[v] = cloneBytes(secret)
+	}
+	d := &Deriver{
+		scheme:   cfg.Scheme,
+		masters:  masters,
+		version:  cfg.Version,
+		label:    []byte(cfg.Label),
+		readFunc: cfg.Rand,
+	}
+	if d.readFunc == nil {
+		d.readFunc = rand.Read
+	}
+	return d, nil
+}
+
+// NewID generates a new macaroon ID tagged with the current master secret version.
+func (d *Deriver) NewID() ([]byte, error) {
+	id := make([]byte, versionSize+nonceSize)
+	binary.BigEndian.PutUint32(id, d.version)
+	n, err := d.readFunc(id[versionSize:])
+	if err != nil {
+		return nil, fmt.Errorf("rootkey: failed to generate nonce: %w", err)
+	}
+	if n != nonceSize {
+		return nil, fmt.Errorf("rootkey: not enough random bytes for nonce. expected: %d, got: %d", nonceSize, n)
+	}
+	return id, nil
+}
+
+// DeriveKey derives the root key for the macaroon ID.
+// If the ID was not generated by a Deriver, or its version is unknown, the error wraps [mack.ErrRootKeyNotFound].
+func (d *Deriver) DeriveKey(id []byte) ([]byte, error) {
+	if len(id) != versionSize+nonceSize {
+		return nil, fmt.Errorf("%w: invalid macaroon id length %d", mack.ErrRootKeyNotFound, len(id))
+	}
+	version := binary.BigEndian.Uint32(id)
+	master, ok := d.masters[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown master secret version %d", mack.ErrRootKeyNotFound, version)
+	}
+	info := make([]byte, 0, len(d.label)+1+len(id))
+	info = append(info, d.label...)
+	info = append(info, 0)
+	info = append(info, id...)
+	return d.scheme.DeriveKey(master, nil, info)
+}
+
+// RootKey generates a new macaroon ID and derives its root key.
+func (d *Deriver) RootKey(_ context.Context) ([]byte, []byte, error) {
+	id, err := d.NewID()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := d.DeriveKey(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, key, nil
+}
+
+// Get derives the root key for the macaroon ID.
+func (d *Deriver) Get(_ context.Context, id []byte) ([]byte, error) {
+	return d.DeriveKey(id)
+}
+
+var _ mack.RootKeyStore = (*Deriver)(nil)
@@ -0,0 +1,102 @@
+package rootkey_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+	"github.com/justenwalker/mack/rootkey"
+)
+
+func TestDeriver(t *testing.T) {
+	ctx := context.Background()
+	sch := testhelpers.NewScheme(t)
+	v1 := []byte(`master-secret-v1`)
+	v2 := []byte(`master-secret-v2`)
+	d1, err := rootkey.NewDeriver(rootkey.DeriverConfig{
+		Scheme:  sch,
+		Masters: map[uint32][]byte{1: v1},
+		Version: 1,
+		Label:   "test",
+	})
+	if err != nil {
+		t.Fatalf("NewDeriver: %v", err)
+	}
+	m1, err := sch.NewMacaroonWithStore(ctx, d1, "loc", []byte(`a > 1`))
+	if err != nil {
+		t.Fatalf("NewMacaroonWithStore: %v", err)
+	}
+	// rotate to v2, keeping v1 for verification
+	d2, err := rootkey.NewDeriver(rootkey.DeriverConfig{
+		Scheme:  sch,
+		Masters: map[uint32][]byte{1: v1, 2: v2},
+		Version: 2,
+		Label:   "test",
+	})
+	if err != nil {
+		t.Fatalf("NewDeriver: %v", err)
+	}
+	m2, err := sch.NewMacaroonWithStore(ctx, d2, "loc", []byte(`a > 1`))
+	if err != nil {
+		t.Fatalf("NewMacaroonWithStore: %v", err)
+	}
+	for i, m := range []mack.Macaroon{m1, m2} {
+		if _, err = sch.VerifyWithStore(ctx, d2, mack.Stack{m}); err != nil {
+			t.Fatalf("VerifyWithStore(m%d): unexpected error: %v", i+1, err)
+		}
+	}
+	k1, err := d1.DeriveKey(m1.ID())
+	if err != nil {
+		t.Fatalf("DeriveKey: %v", err)
+	}
+	k2, err := d2.DeriveKey(m1.ID())
+	if err != nil {
+		t.Fatalf("DeriveKey: %v", err)
+	}
+	if diff := cmp.Diff(k1, k2); diff != "" {
+		t.Fatalf("expected derivation to be deterministic: (-want +got):\n%s", diff)
+	}
+	if _, err = sch.VerifyWithStore(ctx, d1, mack.Stack{m2}); !errors.Is(err, mack.ErrRootKeyNotFound) {
+		t.Fatalf("expected unknown version to fail with ErrRootKeyNotFound, got %v", err)
+	}
+	other, err := rootkey.NewDeriver(rootkey.DeriverConfig{
+		Scheme:  sch,
+		Masters: map[uint32][]byte{1: v1},
+		Version: 1,
+		Label:   "other",
+	})
+	if err != nil {
+		t.Fatalf("NewDeriver: %v", err)
+	}
+	k3, err := other.DeriveKey(m1.ID())
+	if err != nil {
+		t.Fatalf("DeriveKey: %v", err)
+	}
+	if bytes.Equal(k1, k3) {
+		t.Fatalf("expected the label to change the derived key")
+	}
+}
+
+func TestNewDeriver_errors(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	tests := []struct {
+		name string
+		cfg  rootkey.DeriverConfig
+	}{
+		{name: "no-scheme", cfg: rootkey.DeriverConfig{Masters: map[uint32][]byte{0: []byte(`x`)}}},
+		{name: "no-current-version", cfg: rootkey.DeriverConfig{Scheme: sch, Masters: map[uint32][]byte{0: []byte(`x`)}, Version: 1}},
+		{name: "empty-secret", cfg: rootkey.DeriverConfig{Scheme: sch, Masters: map[uint32][]byte{0: nil}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rootkey.NewDeriver(tt.cfg); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
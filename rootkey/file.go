@@ -0,0 +1,198 @@
+package rootkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/justenwalker/mack"
+)
+
+// FileStore is a [mack.RootKeyStore] which persists its keys to a JSON file.
+//
+// The file is rewritten atomically whenever a key is generated or expired. Processes sharing the file
+// merge their keys with the keys in the file by key ID before writing it, holding a lock of the file
+// <path>.lock, so that keys generated by one process are never discarded by another.
+// If a key is not found, the file is reloaded before giving up, so that keys generated by another
+// process can be found. The file is only reloaded if it was replaced since it was last read.
+// The file contains secret key material, so it is created with mode 0600.
+type FileStore struct {
+	kr     *keyring
+	path   string
+	loaded fs.FileInfo // the file as it was last loaded or saved.
+	dirty  bool        // the keys were modified, but could not be saved.
+}
+
+type fileJSON struct {
+	Keys []keyJSON `json:"keys"`
+}
+
+type keyJSON struct {
+	ID      []byte    `json:"id"`
+	Key     []byte    `json:"key"`
+	Created time.Time `json:"created"`
+}
+
+// NewFileStore creates a FileStore backed by the file at path, loading any keys it already contains.
+func NewFileStore(path string, cfg Config) (*FileStore, error) {
+	kr, err := newKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{kr: kr, path: path}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if err = s.sync(false); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RootKey returns a new macaroon ID and the current root key, rotating the key if necessary.
+// If the keys can not be saved, an error is returned, and the save is retried by the next call.
+func (s *FileStore) RootKey(_ context.Context) ([]byte, []byte, error) {
+	id, key, changed, err := s.kr.rootKey()
+	s.kr.mu.Lock()
+	defer s.kr.mu.Unlock()
+	if changed || s.dirty {
+		if serr := s.sync(true); serr != nil {
+			s.dirty = true
+			return nil, nil, serr
+		}
+		s.dirty = false
+	}
+	return id, key, err
+}
+
+// Get returns the root key for the macaroon ID.
+func (s *FileStore) Get(_ context.Context, id []byte) ([]byte, error) {
+	key, err := s.kr.get(id)
+	if !errors.Is(err, mack.ErrRootKeyNotFound) || len(id) != keyIDSize+nonceSize {
+		return key, err
+	}
+	s.kr.mu.Lock()
+	if !s.modified() {
+		s.kr.mu.Unlock()
+		return key, err
+	}
+	lerr := s.sync(false)
+	s.kr.mu.Unlock()
+	if lerr != nil {
+		return nil, lerr
+	}
+	return s.kr.get(id)
+}
+
+// Keys returns a copy of the keys currently held by the store, oldest first.
+func (s *FileStore) Keys() []Key {
+	s.kr.mu.Lock()
+	defer s.kr.mu.Unlock()
+	return s.kr.snapshot()
+}
+
+// modified returns true if the file was replaced since it was last loaded or saved. kr.mu must be held.
+func (s *FileStore) modified() bool {
+	fi, err := os.Stat(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil || s.loaded == nil {
+		return true
+	}
+	return !os.SameFile(fi, s.loaded) || !fi.ModTime().Equal(s.loaded.ModTime()) || fi.Size() != s.loaded.Size()
+}
+
+// sync merges the keys in the file with the keys in memory while holding the lock of the file,
+// then writes the merged keys back to the file if save is true. kr.mu must be held.
+func (s *FileStore) sync(save bool) error {
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("rootkey: failed to lock key file: %w", err)
+	}
+	defer unlock()
+	if err = s.load(); err != nil {
+		return err
+	}
+	s.kr.expire(s.kr.cfg.Now())
+	if !save {
+		return nil
+	}
+	return s.save()
+}
+
+// load merges the contents of the file into the keys. kr.mu and the lock of the file must be held.
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("rootkey: failed to read key file: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("rootkey: failed to read key file: %w", err)
+	}
+	bs, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("rootkey: failed to read key file: %w", err)
+	}
+	var js fileJSON
+	if err = json.Unmarshal(bs, &js); err != nil {
+		return fmt.Errorf("rootkey: failed to decode key file '%s': %w", s.path, err)
+	}
+	keys := make([]Key, len(js.Keys))
+	for i, k := range js.Keys {
+		if len(k.ID) != keyIDSize || len(k.Key) != s.kr.cfg.Scheme.KeySize() {
+			return fmt.Errorf("rootkey: invalid key %d in key file '%s'", i, s.path)
+		}
+		keys[i] = Key(k)
+	}
+	s.kr.merge(keys)
+	s.loaded = fi
+	return nil
+}
+
+// save atomically writes the keys to the file. kr.mu and the lock of the file must be held.
+func (s *FileStore) save() error {
+	js := fileJSON{Keys: make([]keyJSON, len(s.kr.keys))}
+	for i, k := range s.kr.keys {
+		js.Keys[i] = keyJSON(k)
+	}
+	bs, err := json.Marshal(js)
+	if err != nil {
+		return fmt.Errorf("rootkey: failed to encode key file: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("rootkey: failed to create key file: %w", err)
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck // no longer exists after a successful rename
+	if _, err = f.Write(bs); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("rootkey: failed to write key file: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("rootkey: failed to write key file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("rootkey: failed to write key file: %w", err)
+	}
+	if err = os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("rootkey: failed to replace key file: %w", err)
+	}
+	if s.loaded, err = os.Stat(s.path); err != nil {
+		return fmt.Errorf("rootkey: failed to replace key file: %w", err)
+	}
+	return nil
+}
+
+var _ mack.RootKeyStore = (*FileStore)(nil)
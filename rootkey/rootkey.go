@@ -0,0 +1,252 @@
+// Package rootkey provides implementations of [mack.RootKeyStore].
+//
+// A [MemoryStore] keeps its root keys in memory, and a [FileStore] additionally persists them to a file
+// so that they survive restarts and can be shared by processes on the same host.
+//
+// Both stores rotate the root key used to mint new macaroons after [Config.RotationPeriod],
+// and forget old root keys after [Config.Expiry], so macaroons minted with them no longer verify.
+//
+// Each macaroon ID issued by a store is the 8-byte identifier of the root key followed by a 16-byte random nonce.
+//
+// Alternatively, a [Deriver] stores nothing at all: it derives each root key from a versioned master secret
+// and the macaroon ID.
+package rootkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justenwalker/mack"
+)
+
+const (
+	keyIDSize = 8
+	nonceSize = 16
+)
+
+// Config configures a root key store.
+type Config struct {
+	// Scheme is the cryptographic scheme used for Macaroons (Required).
+	// It determines the size of the generated root keys.
+	Scheme *mack.Scheme
+	// RotationPeriod is how long a root key is used to mint new macaroons before a new one is generated.
+	// If zero, the root key is never rotated.
+	RotationPeriod time.Duration
+	// Expiry is how long after its creation a root key can be used to verify macaroons.
+	// If zero, root keys never expire. Otherwise, it must not be shorter than the RotationPeriod.
+	Expiry time.Duration
+	// Now returns the current time. If nil, [time.Now] is used.
+	Now func() time.Time
+	// Rand is the source of random bytes for keys and nonces. If nil, [crypto/rand.Read] is used.
+	Rand func([]byte) (int, error)
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Scheme == nil {
+		return errors.New("cfg.Scheme is nil")
+	}
+	if cfg.RotationPeriod < 0 || cfg.Expiry < 0 {
+		return errors.New("cfg.RotationPeriod and cfg.Expiry must not be negative")
+	}
+	if cfg.Expiry > 0 && cfg.Expiry < cfg.RotationPeriod {
+		return fmt.Errorf("cfg.Expiry (%v) is shorter than cfg.RotationPeriod (%v)", cfg.Expiry, cfg.RotationPeriod)
+	}
+	return nil
+}
+
+// Key is a root key held by a store.
+type Key struct {
+	// ID identifies the key. It is the prefix of every macaroon ID minted with it.
+	ID []byte
+	// Key is the secret root key.
+	Key []byte
+	// Created is when the key was generated.
+	Created time.Time
+}
+
+// keyring contains the rotation and expiry logic shared by the stores.
+type keyring struct {
+	cfg  Config
+	mu   sync.Mutex
+	keys []Key // sorted by creation, the last key is the current key.
+}
+
+func newKeyring(cfg Config) (*keyring, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.Read
+	}
+	return &keyring{cfg: cfg}, nil
+}
+
+// rootKey returns a new macaroon id and the current root key, reporting whether the key set was modified.
+func (kr *keyring) rootKey() ([]byte, []byte, bool, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	now := kr.cfg.Now()
+	changed := kr.expire(now)
+	if n := len(kr.keys); n == 0 || (kr.cfg.RotationPeriod > 0 && now.Sub(kr.keys[n-1].Created) >= kr.cfg.RotationPeriod) {
+		k, err := kr.generate(now)
+		if err != nil {
+			return nil, nil, changed, err
+		}
+		kr.keys = append(kr.keys, k)
+		changed = true
+	}
+	current := kr.keys[len(kr.keys)-1]
+	id := make([]byte, keyIDSize+nonceSize)
+	copy(id, current.ID)
+	if err := kr.read(id[keyIDSize:]); err != nil {
+		return nil, nil, changed, fmt.Errorf("rootkey: failed to generate nonce: %w", err)
+	}
+	return id, cloneBytes(current.Key), changed, nil
+}
+
+// get returns the root key for the macaroon id.
+func (kr *keyring) get(id []byte) ([]byte, error) {
+	if len(id) != keyIDSize+nonceSize {
+		return nil, fmt.Errorf("%w: invalid macaroon id length %d", mack.ErrRootKeyNotFound, len(id))
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	now := kr.cfg.Now()
+	for i := range kr.keys {
+		if !bytes.Equal(kr.keys[i].ID, id[:keyIDSize]) {
+			continue
+		}
+		if kr.expired(&kr.keys[i], now) {
+			break
+		}
+		return cloneBytes(kr.keys[i].Key), nil
+	}
+	return nil, fmt.Errorf("%w: no root key for id %x", mack.ErrRootKeyNotFound, id[:keyIDSize])
+}
+
+// expire removes expired keys, reporting whether any were removed.
+func (kr *keyring) expire(now time.Time) bool {
+	n := 0
+	for i := range kr.keys {
+		if !kr.expired(&kr.keys[i], now) {
+			kr.keys[n] = kr.keys[i]
+			n++
+		}
+	}
+	changed := n != len(kr.keys)
+	kr.keys = kr.keys[:n]
+	return changed
+}
+
+func (kr *keyring) expired(k *Key, now time.Time) bool {
+	return kr.cfg.Expiry > 0 && now.Sub(k.Created) >= kr.cfg.Expiry
+}
+
+func (kr *keyring) generate(now time.Time) (Key, error) {
+	k := Key{
+		ID:      make([]byte, keyIDSize),
+		Key:     make([]byte, kr.cfg.Scheme.KeySize()),
+		Created: now,
+	}
+	if err := kr.read(k.ID); err != nil {
+		return Key{}, fmt.Errorf("rootkey: failed to generate key id: %w", err)
+	}
+	if err := kr.read(k.Key); err != nil {
+		return Key{}, fmt.Errorf("rootkey: failed to generate key: %w", err)
+	}
+	return k, nil
+}
+
+func (kr *keyring) read(bs []byte) error {
+	n, err := kr.cfg.Rand(bs)
+	if err != nil {
+		return err
+	}
+	if n != len(bs) {
+		return fmt.Errorf("not enough random bytes. expected: %d, got: %d", len(bs), n)
+	}
+	return nil
+}
+
+// merge adds the keys which are not already in the keyring by ID, keeping the keyring sorted by creation.
+// kr.mu must be held.
+func (kr *keyring) merge(keys []Key) {
+	n := len(kr.keys)
+outer:
+	for _, k := range keys {
+		for i := 0; i < n; i++ {
+			if bytes.Equal(kr.keys[i].ID, k.ID) {
+				continue outer
+			}
+		}
+		kr.keys = append(kr.keys, k)
+	}
+	if len(kr.keys) != n {
+		sort.SliceStable(kr.keys, func(i, j int) bool {
+			return kr.keys[i].Created.Before(kr.keys[j].Created)
+		})
+	}
+}
+
+// snapshot returns a copy of the keys.
+func (kr *keyring) snapshot() []Key {
+	keys := make([]Key, len(kr.keys))
+	for i := range kr.keys {
+		keys[i] = Key{
+			ID:      cloneBytes(kr.keys[i].ID),
+			Key:     cloneBytes(kr.keys[i].Key),
+			Created: kr.keys[i].Created,
+		}
+	}
+	return keys
+}
+
+// MemoryStore is a [mack.RootKeyStore] which keeps its keys in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	kr *keyring
+}
+
+// NewMemoryStore creates a new, empty MemoryStore.
+func NewMemoryStore(cfg Config) (*MemoryStore, error) {
+	kr, err := newKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryStore{kr: kr}, nil
+}
+
+// RootKey returns a new macaroon ID and the current root key, rotating the key if necessary.
+func (s *MemoryStore) RootKey(_ context.Context) ([]byte, []byte, error) {
+	id, key, _, err := s.kr.rootKey()
+	return id, key, err
+}
+
+// Get returns the root key for the macaroon ID.
+func (s *MemoryStore) Get(_ context.Context, id []byte) ([]byte, error) {
+	return s.kr.get(id)
+}
+
+// Keys returns a copy of the keys currently held by the store, oldest first.
+func (s *MemoryStore) Keys() []Key {
+	s.kr.mu.Lock()
+	defer s.kr.mu.Unlock()
+	return s.kr.snapshot()
+}
+
+func cloneBytes(vs []byte) []byte {
+	bs := make([]byte, len(vs))
+	copy(bs, vs)
+	return bs
+}
+
+var _ mack.RootKeyStore = (*MemoryStore)(nil)
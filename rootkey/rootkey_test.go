@@ -0,0 +1,259 @@
+package rootkey_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+	"github.com/justenwalker/mack/rootkey"
+)
+
+type store interface {
+	mack.RootKeyStore
+	Keys() []rootkey.Key
+}
+
+func TestStores(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T, cfg rootkey.Config) store
+	}{
+		{
+			name: "memory",
+			store: func(t *testing.T, cfg rootkey.Config) store {
+				t.Helper()
+				s, err := rootkey.NewMemoryStore(cfg)
+				if err != nil {
+					t.Fatalf("NewMemoryStore: %v", err)
+				}
+				return s
+			},
+		},
+		{
+			name: "file",
+			store: func(t *testing.T, cfg rootkey.Config) store {
+				t.Helper()
+				s, err := rootkey.NewFileStore(filepath.Join(t.TempDir(), "keys.json"), cfg)
+				if err != nil {
+					t.Fatalf("NewFileStore: %v", err)
+				}
+				return s
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sch := testhelpers.NewScheme(t)
+			now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+			s := tt.store(t, rootkey.Config{
+				Scheme:         sch,
+				RotationPeriod: time.Hour,
+				Expiry:         3 * time.Hour,
+				Now:            func() time.Time { return now },
+				Rand:           testhelpers.ReadRandom,
+			})
+			m1, err := sch.NewMacaroonWithStore(ctx, s, "loc", []byte(`a > 1`))
+			if err != nil {
+				t.Fatalf("NewMacaroonWithStore: %v", err)
+			}
+			m2, err := sch.NewMacaroonWithStore(ctx, s, "loc", []byte(`a > 1`))
+			if err != nil {
+				t.Fatalf("NewMacaroonWithStore: %v", err)
+			}
+			if bytes.Equal(m1.ID(), m2.ID()) {
+				t.Fatalf("expected unique macaroon ids")
+			}
+			if n := len(s.Keys()); n != 1 {
+				t.Fatalf("expected 1 key before rotation, got %d", n)
+			}
+			now = now.Add(time.Hour)
+			m3, err := sch.NewMacaroonWithStore(ctx, s, "loc", []byte(`a > 1`))
+			if err != nil {
+				t.Fatalf("NewMacaroonWithStore: %v", err)
+			}
+			if n := len(s.Keys()); n != 2 {
+				t.Fatalf("expected 2 keys after rotation, got %d", n)
+			}
+			for i, m := range []mack.Macaroon{m1, m2, m3} {
+				if _, err = sch.VerifyWithStore(ctx, s, mack.Stack{m}); err != nil {
+					t.Fatalf("VerifyWithStore(m%d): unexpected error: %v", i+1, err)
+				}
+			}
+			now = now.Add(2 * time.Hour)
+			_, err = sch.VerifyWithStore(ctx, s, mack.Stack{m1})
+			if !errors.Is(err, mack.ErrRootKeyNotFound) || !errors.Is(err, mack.ErrVerificationFailed) {
+				t.Fatalf("VerifyWithStore(m1): expected expired key to fail verification, got %v", err)
+			}
+			if _, err = sch.VerifyWithStore(ctx, s, mack.Stack{m3}); err != nil {
+				t.Fatalf("VerifyWithStore(m3): unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStore_persist(t *testing.T) {
+	ctx := context.Background()
+	sch := testhelpers.NewScheme(t)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s1, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	s2, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	m, err := sch.NewMacaroonWithStore(ctx, s1, "loc", []byte(`a > 1`))
+	if err != nil {
+		t.Fatalf("NewMacaroonWithStore: %v", err)
+	}
+	// s2 was loaded before the key was generated, so it must reload the file.
+	if _, err = sch.VerifyWithStore(ctx, s2, mack.Stack{m}); err != nil {
+		t.Fatalf("VerifyWithStore: unexpected error: %v", err)
+	}
+	s3, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	if n := len(s3.Keys()); n != 1 {
+		t.Fatalf("expected 1 key to be loaded, got %d", n)
+	}
+}
+
+func TestFileStore_shared(t *testing.T) {
+	ctx := context.Background()
+	sch := testhelpers.NewScheme(t)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	newStore := func() *rootkey.FileStore {
+		s, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+		if err != nil {
+			t.Fatalf("NewFileStore: %v", err)
+		}
+		return s
+	}
+	a := newStore()
+	b := newStore()
+	idA, keyA, err := a.RootKey(ctx)
+	if err != nil {
+		t.Fatalf("a.RootKey: %v", err)
+	}
+	// b was loaded before a generated its key, so it generates its own, which must not discard a's key.
+	idB, keyB, err := b.RootKey(ctx)
+	if err != nil {
+		t.Fatalf("b.RootKey: %v", err)
+	}
+	if bytes.Equal(keyA, keyB) {
+		t.Fatalf("expected the stores to generate different keys")
+	}
+	for _, tt := range []struct {
+		name  string
+		store *rootkey.FileStore
+		id    []byte
+		key   []byte
+	}{
+		{name: "a/b", store: a, id: idB, key: keyB},
+		{name: "a/a", store: a, id: idA, key: keyA},
+		{name: "b/a", store: b, id: idA, key: keyA},
+		{name: "b/b", store: b, id: idB, key: keyB},
+		{name: "restarted/a", store: newStore(), id: idA, key: keyA},
+		{name: "restarted/b", store: newStore(), id: idB, key: keyB},
+	} {
+		key, gerr := tt.store.Get(ctx, tt.id)
+		if gerr != nil {
+			t.Fatalf("%s: Get: unexpected error: %v", tt.name, gerr)
+		}
+		if !bytes.Equal(key, tt.key) {
+			t.Fatalf("%s: Get: returned the wrong key", tt.name)
+		}
+	}
+	if n := len(newStore().Keys()); n != 2 {
+		t.Fatalf("expected 2 keys in the file, got %d", n)
+	}
+}
+
+func TestFileStore_Get_unchanged(t *testing.T) {
+	ctx := context.Background()
+	sch := testhelpers.NewScheme(t)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	id, _, err := s.RootKey(ctx)
+	if err != nil {
+		t.Fatalf("RootKey: %v", err)
+	}
+	// An unknown key ID does not reload the file unless it was replaced, so a missing lock file is not recreated.
+	if err = os.Remove(path + ".lock"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Remove: %v", err)
+	}
+	unknown := bytes.Repeat([]byte{0xff}, len(id))
+	if _, err = s.Get(ctx, unknown); !errors.Is(err, mack.ErrRootKeyNotFound) {
+		t.Fatalf("Get: expected ErrRootKeyNotFound, got %v", err)
+	}
+	if _, err = os.Stat(path + ".lock"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the file not to be reloaded, got %v", err)
+	}
+}
+
+func TestNewMemoryStore_errors(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	tests := []struct {
+		name string
+		cfg  rootkey.Config
+	}{
+		{name: "no-scheme", cfg: rootkey.Config{}},
+		{name: "negative", cfg: rootkey.Config{Scheme: sch, RotationPeriod: -1}},
+		{name: "expiry-before-rotation", cfg: rootkey.Config{Scheme: sch, RotationPeriod: time.Hour, Expiry: time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rootkey.NewMemoryStore(tt.cfg); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestFileStore_RootKey_saveFailed(t *testing.T) {
+	ctx := context.Background()
+	sch := testhelpers.NewScheme(t)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	// a directory in place of the key file can not be read or replaced.
+	if err = os.Mkdir(path, 0o700); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if _, _, err = s.RootKey(ctx); err == nil {
+		t.Fatalf("RootKey: expected the save to fail")
+	}
+	if err = os.Remove(path); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	id, key, err := s.RootKey(ctx)
+	if err != nil {
+		t.Fatalf("RootKey: unexpected error: %v", err)
+	}
+	restarted, err := rootkey.NewFileStore(path, rootkey.Config{Scheme: sch})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	got, err := restarted.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: expected the key to be saved by the next RootKey: %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Fatalf("Get: returned the wrong key")
+	}
+}
@@ -133,8 +133,7 @@ func (s *Scheme) NewMacaroon(loc string, id []byte, key []byte, caveats ...[]byt
 			CID: c,
 		}
 	}
-	m = m.addCaveats(s, rcs...)
-	return m, nil
+	return m.addCaveats(s, rcs...)
 }
 
 func (s *Scheme) newMacaroon(loc string, id []byte, key []byte) (Macaroon, error) {
@@ -156,13 +155,110 @@ func (s *Scheme) KeySize() int {
 }
 
 // Verify the cryptographic signatures of the entire macaroon stack using the root key provided.
-func (s *Scheme) Verify(ctx context.Context, key []byte, stack Stack) (VerifiedStack, error) {
-	v := getVerifyContext(ctx)
-	v.init(stack)
+// Options may be given to perform additional checks on the stack, such as [WithRevoker] and [WithLimits].
+//
+// The context is checked before each macaroon and caveat is verified, so that verifying a large stack may be aborted.
+// If the context is done, the error wraps the error of the context, ie: context.Canceled.
+func (s *Scheme) Verify(ctx context.Context, key []byte, stack Stack, opts ...VerifyOption) (VerifiedStack, error) {
+	vr := verifier{
+		ctx:      ctx,
+		s:        s,
+		stack:    stack,
+		trace:    getVerifyContext(ctx),
+		observer: getVerifyObserver(ctx),
+	}
+	vr.trace.init(stack)
+	vr.observe(VerifyEvent{Kind: VerifyEventStart, CaveatIndex: -1})
+	err := vr.verifyStack(key, newVerifyOptions(opts))
+	vr.trace.finish()
+	if err != nil {
+		vr.observe(VerifyEvent{Kind: VerifyEventFailure, StackIndex: vr.failStack, CaveatIndex: vr.failCaveat, Err: err})
+	}
+	vr.observe(VerifyEvent{Kind: VerifyEventEnd, CaveatIndex: -1, Err: err})
+	if err != nil {
+		return VerifiedStack{}, err
+	}
+	return VerifiedStack{
+		verified: true,
+		stack:    stack,
+		seals:    vr.seals,
+	}, nil
+}
+
+// VerifyAny verifies the macaroon stack like [Scheme.Verify], accepting any one of the candidate root keys.
+// This is useful during root key rotation, when a macaroon may have been minted under either the old or the new key.
+// It returns the index of the key which verified the stack.
+//
+// The signature of the target macaroon is checked against each key first, which is much cheaper than
+// a full verification, so that the stack is only fully verified with the keys that could have minted it.
+// Traces and [VerifyEvent]s are only recorded for those keys.
+// The [Limits] are checked, and the context is checked for cancellation, before any signature is computed.
+// The signature checks of all the keys together count against MaxOperations, as does each full verification.
+// If no key verifies the stack, the index is -1, and the error joins the error for each key.
+func (s *Scheme) VerifyAny(ctx context.Context, keys [][]byte, stack Stack, opts ...VerifyOption) (VerifiedStack, int, error) {
+	if len(keys) == 0 {
+		return VerifiedStack{}, -1, fmt.Errorf("%w: no root keys", ErrInvalidArgument)
+	}
+	if len(stack) == 0 {
+		return VerifiedStack{}, -1, fmt.Errorf("%w: empty stack", ErrInvalidArgument)
+	}
+	target := stack.Target()
+	vr := verifier{ctx: ctx, s: s}
+	if o := newVerifyOptions(opts); o != nil {
+		if err := o.limits.CheckStack(stack); err != nil {
+			return VerifiedStack{}, -1, verificationError(VerificationLimitExceeded, target, 0, err)
+		}
+		vr.maxOps = o.limits.MaxOperations
+	}
+	sigbuf := s.getKeyBuffer()
+	defer s.releaseKeyBuffer(sigbuf)
+	errs := make([]error, 0, len(keys))
+	for i, key := range keys {
+		if err := vr.checkContext(); err != nil {
+			return VerifiedStack{}, -1, err
+		}
+		if len(key) != s.keySize {
+			errs = append(errs, fmt.Errorf("key %d: %w: invalid key size. need=%d, got=%d", i, ErrInvalidArgument, s.keySize, len(key)))
+			continue
+		}
+		ok, err := target.signedBy(&vr, key, *sigbuf)
+		if errors.Is(err, ErrLimitExceeded) {
+			return VerifiedStack{}, -1, err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key %d: %w", i, err))
+			continue
+		}
+		if !ok {
+			errs = append(errs, fmt.Errorf("key %d: %w", i, verificationError(VerificationSignatureMismatch, target, 0, errors.New("macaroon.verify: target signature did not match"))))
+			continue
+		}
+		vs, err := s.Verify(ctx, key, stack, opts...)
+		if err == nil {
+			return vs, i, nil
+		}
+		errs = append(errs, fmt.Errorf("key %d: %w", i, err))
+	}
+	return VerifiedStack{}, -1, errors.Join(errs...)
+}
+
+func (vr *verifier) verifyStack(key []byte, o *verifyOptions) error {
+	s := vr.s
+	stack := vr.stack
+	v := vr.trace
 	if len(key) != s.keySize {
-		return VerifiedStack{}, fmt.Errorf("%w: invalid key size. need=%d, got=%d", ErrInvalidArgument, s.keySize, len(key))
+		return fmt.Errorf("%w: invalid key size. need=%d, got=%d", ErrInvalidArgument, s.keySize, len(key))
 	}
 	target := &stack[0]
+	if o != nil {
+		if err := o.limits.CheckStack(stack); err != nil {
+			err = verificationError(VerificationLimitExceeded, target, 0, err)
+			v.fail(0, err)
+			return err
+		}
+		vr.maxDepth = o.limits.MaxDepth
+		vr.maxOps = o.limits.MaxOperations
+	}
 	discharge := stack[1:]
 	var discharged []byte
 	if len(discharge) > 32 {
@@ -174,24 +270,27 @@ func (s *Scheme) Verify(ctx context.Context, key []byte, stack Stack) (VerifiedS
 	keyBuf := s.getKeyBuffer()
 	copy(*keyBuf, key)
 	defer s.releaseKeyBuffer(keyBuf)
-	if err := target.verify(s, stack, *keyBuf, *keyBuf, v, 0, discharged); err != nil {
-		return VerifiedStack{}, err
+	if err := target.verify(vr, *keyBuf, *keyBuf, 0, discharged, 0); err != nil {
+		return err
 	}
-	for i := range discharged {
-		if discharged[i] == 0 {
-			err := validationError(target, fmt.Errorf("discharge macaroon %d was unused", i))
+	for i, n := range discharged {
+		if n == 0 {
+			err := verificationError(VerificationUnusedDischarge, &stack[i+1], i+1, fmt.Errorf("discharge macaroon %d was unused", i))
 			v.fail(0, err)
-			return VerifiedStack{}, err
+			vr.failedAt(i+1, -1)
+			return err
 		}
-		if discharged[i] > 1 {
-			err := validationError(target, fmt.Errorf("discharge macaroon %d was used more than once", i))
+		if n > 1 {
+			err := verificationError(VerificationReusedDischarge, &stack[i+1], i+1, fmt.Errorf("discharge macaroon %d was used more than once", i))
 			v.fail(0, err)
-			return VerifiedStack{}, err
+			vr.failedAt(i+1, -1)
+			return err
 		}
 	}
-	return VerifiedStack{
-		stack: stack,
-	}, nil
+	if o != nil && o.revoker != nil {
+		return vr.checkRevoked(o.revoker)
+	}
+	return nil
 }
 
 // PrepareStack prepares the set of discharge macaroons for a request, assembling them with the target Macaroon into a [Stack].
@@ -214,7 +313,7 @@ func (s *Scheme) AddFirstPartyCaveat(m *Macaroon, cID []byte) (Macaroon, error)
 	if len(cID) == 0 {
 		return Macaroon{}, errors.New("AddFirstPartyCaveat: empty predicate")
 	}
-	return m.addFirstPartyCaveat(s, cID), nil
+	return m.addFirstPartyCaveat(s, cID)
 }
 
 // AddThirdPartyCaveat appends a third party caveat, returning a new Macaroon with the caveat appended.
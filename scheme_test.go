@@ -76,3 +76,162 @@ func TestScheme_Validate(t *testing.T) {
 		t.Logf("validation failed: %v <%[1]TB>", errors.Unwrap(err))
 	})
 }
+
+func TestScheme_AddFirstPartyCaveat_tooLarge(t *testing.T) {
+	if macaroon.MaxFieldSize > 1<<20 {
+		t.Skip("MaxFieldSize is too large to test")
+	}
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte(`id`), testhelpers.RootKey, []byte(`a = 1`))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	large := make([]byte, macaroon.MaxFieldSize+1)
+	if _, err = sch.AddFirstPartyCaveat(&m, large); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("AddFirstPartyCaveat: expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err = sch.AddThirdPartyCaveat(&m, testhelpers.ThirdPartyKey, large, "3p"); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("AddThirdPartyCaveat: expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err = sch.NewMacaroon("loc", large, testhelpers.RootKey, []byte(`a = 1`)); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("NewMacaroon: expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestScheme_VerifyAny(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	oldKey := make([]byte, len(testhelpers.RootKey))
+	testhelpers.MustReadRandom(oldKey)
+	var starts int
+	ctx := macaroon.WithVerifyObserver(context.Background(), macaroon.VerifyObserverFunc(func(_ context.Context, ev macaroon.VerifyEvent) {
+		if ev.Kind == macaroon.VerifyEventStart {
+			starts++
+		}
+	}))
+	keys := [][]byte{oldKey, []byte("short"), testhelpers.RootKey}
+	vs, idx, err := fx.Scheme.VerifyAny(ctx, keys, fx.Stack)
+	if err != nil {
+		t.Fatalf("VerifyAny: unexpected error: %v", err)
+	}
+	if idx != 2 {
+		t.Fatalf("VerifyAny: expected key index 2, got %d", idx)
+	}
+	if string(vs.ID()) != "target" || !vs.Verified() {
+		t.Fatalf("VerifyAny: unexpected stack %q, verified=%v", vs.ID(), vs.Verified())
+	}
+	if starts != 1 {
+		t.Fatalf("VerifyAny: expected a single full verification, got %d", starts)
+	}
+
+	_, idx, err = fx.Scheme.VerifyAny(ctx, keys[:2], fx.Stack)
+	if idx != -1 {
+		t.Fatalf("VerifyAny: expected key index -1, got %d", idx)
+	}
+	if !errors.Is(err, macaroon.ErrVerificationFailed) || !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("VerifyAny: expected the errors of every key, got %v", err)
+	}
+
+	_, idx, err = fx.Scheme.VerifyAny(ctx, keys, fx.Stack[:1])
+	if idx != -1 || !errors.Is(err, macaroon.ErrVerificationFailed) {
+		t.Fatalf("VerifyAny: expected verification of the matching key to fail, got %d: %v", idx, err)
+	}
+
+	if _, _, err = fx.Scheme.VerifyAny(ctx, nil, fx.Stack); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("VerifyAny: expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestScheme_VerifyAny_limits(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	oldKey := make([]byte, len(testhelpers.RootKey))
+	testhelpers.MustReadRandom(oldKey)
+	keys := [][]byte{oldKey, oldKey, testhelpers.RootKey}
+	var starts int
+	ctx := macaroon.WithVerifyObserver(context.Background(), macaroon.VerifyObserverFunc(func(_ context.Context, ev macaroon.VerifyEvent) {
+		if ev.Kind == macaroon.VerifyEventStart {
+			starts++
+		}
+	}))
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		limits   macaroon.Limits
+		expected error
+	}{
+		{name: "caveats", ctx: ctx, limits: macaroon.Limits{MaxCaveats: 1}, expected: macaroon.ErrLimitExceeded},
+		// each signature check of the target is 3 operations: the ID and 2 caveats.
+		{name: "operations", ctx: ctx, limits: macaroon.Limits{MaxOperations: 5}, expected: macaroon.ErrLimitExceeded},
+		{name: "canceled", ctx: canceled, expected: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			starts = 0
+			_, idx, err := fx.Scheme.VerifyAny(tt.ctx, keys, fx.Stack, macaroon.WithLimits(tt.limits))
+			if idx != -1 || !errors.Is(err, tt.expected) {
+				t.Fatalf("VerifyAny: expected %v, got %d: %v", tt.expected, idx, err)
+			}
+			if starts != 0 {
+				t.Fatalf("VerifyAny: expected no full verification, got %d", starts)
+			}
+		})
+	}
+	if _, idx, err := fx.Scheme.VerifyAny(ctx, keys, fx.Stack, macaroon.WithLimits(macaroon.Limits{MaxOperations: 20})); err != nil || idx != 2 {
+		t.Fatalf("VerifyAny: expected key index 2, got %d: %v", idx, err)
+	}
+}
+
+func TestScheme_Verify_canceled(t *testing.T) {
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack); !errors.Is(err, context.Canceled) || errors.Is(err, macaroon.ErrVerificationFailed) {
+		t.Fatalf("Verify: expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var failure macaroon.VerifyEvent
+	ctx = macaroon.WithVerifyObserver(ctx, macaroon.VerifyObserverFunc(func(_ context.Context, ev macaroon.VerifyEvent) {
+		switch ev.Kind {
+		case macaroon.VerifyEventDischargeMatched:
+			cancel()
+		case macaroon.VerifyEventFailure:
+			failure = ev
+		}
+	}))
+	if _, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, fx.Stack); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Verify: expected context.Canceled, got %v", err)
+	}
+	if failure.StackIndex != 1 || failure.CaveatIndex != -1 {
+		t.Fatalf("Verify: expected verification to stop before the discharge, got macaroon %d caveat %d", failure.StackIndex, failure.CaveatIndex)
+	}
+}
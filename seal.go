@@ -0,0 +1,124 @@
+package mack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// SealPrefix is the prefix of a seal caveat, which is followed by the hex-encoded seal tag.
+const SealPrefix = "mack-seal "
+
+// sealInfo is the HKDF info of the seal key. The seal key is derived with HKDF, rather than an HMAC keyed by
+// the macaroon key, so that it can not be the signature of a macaroon minted with the same key.
+var sealInfo = []byte("mack-seal")
+
+// Seal adds a seal caveat to the macaroon, marking every caveat before it as added by the issuer of the macaroon.
+// Caveats added after the seal, ie: by a holder attenuating the macaroon, are delegated. The key must be the key
+// the macaroon was created with: the root key of a target macaroon, or the caveat key of a discharge macaroon.
+//
+// The seal tag is derived from the key and the signature of the macaroon, so it can not be forged, or moved,
+// by a holder who does not know the key. [Scheme.Verify] checks every seal, failing if one does not match,
+// and the [Predicate.Origin] of each first-party predicate in the [VerifiedStack] reports which side of
+// the last seal of its macaroon it is on. Seal caveats are always satisfied by [VerifiedStack.Clear].
+func (s *Scheme) Seal(m *Macaroon, key []byte) (Macaroon, error) {
+	if len(key) != s.keySize {
+		return Macaroon{}, fmt.Errorf("%w: invalid key size. need=%d, got=%d", ErrInvalidArgument, s.keySize, len(key))
+	}
+	tag := s.getKeyBuffer()
+	defer s.releaseKeyBuffer(tag)
+	if err := s.sealKey(key, *tag); err != nil {
+		return Macaroon{}, err
+	}
+	if err := s.hmac.HMAC(*tag, *tag, m.Signature()); err != nil {
+		return Macaroon{}, fmt.Errorf("error executing hmac: %w", err)
+	}
+	caveat := make([]byte, len(SealPrefix)+hex.EncodedLen(s.keySize))
+	copy(caveat, SealPrefix)
+	hex.Encode(caveat[len(SealPrefix):], *tag)
+	return s.AddFirstPartyCaveat(m, caveat)
+}
+
+// sealKey derives the seal key from the key of a macaroon into dst, which must be the size of a key.
+func (s *Scheme) sealKey(key []byte, dst []byte) error {
+	sk, err := s.DeriveKey(key, nil, sealInfo)
+	if err != nil {
+		return err
+	}
+	copy(dst, sk)
+	zeroBytes(sk)
+	return nil
+}
+
+// isSeal returns true if the first-party caveat predicate is a seal caveat.
+func isSeal(predicate []byte) bool {
+	return bytes.HasPrefix(predicate, []byte(SealPrefix))
+}
+
+// sealed returns true if the macaroon has a seal caveat.
+func (m *Macaroon) sealed() bool {
+	for cur := m.data.cursor(); cur.next(); {
+		if !cur.caveat.thirdParty() && isSeal(cur.caveat.ID()) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSeal checks the tag of the seal caveat at index ci of the macaroon at index vi of the stack,
+// given the seal key of the macaroon, and the signature before the seal.
+func (vr *verifier) checkSeal(m *Macaroon, sealKey []byte, sig []byte, c *Caveat, ci int, vi int) error {
+	if err := vr.spend(); err != nil {
+		return caveatVerificationError(VerificationLimitExceeded, m, vi, ci, c.ID(), err)
+	}
+	tag := vr.s.getKeyBuffer()
+	defer vr.s.releaseKeyBuffer(tag)
+	claimed := vr.s.getKeyBuffer()
+	defer vr.s.releaseKeyBuffer(claimed)
+	if err := vr.s.hmac.HMAC(sealKey, *tag, sig); err != nil {
+		return fmt.Errorf("error executing hmac: %w", err)
+	}
+	enc := c.ID()[len(SealPrefix):]
+	if len(enc) != hex.EncodedLen(len(*claimed)) {
+		return caveatVerificationError(VerificationSealMismatch, m, vi, ci, c.ID(), errors.New("macaroon.verify: malformed seal"))
+	}
+	if _, err := hex.Decode(*claimed, enc); err != nil {
+		return caveatVerificationError(VerificationSealMismatch, m, vi, ci, c.ID(), errors.New("macaroon.verify: malformed seal"))
+	}
+	if !hmac.Equal(*claimed, *tag) {
+		return caveatVerificationError(VerificationSealMismatch, m, vi, ci, c.ID(), errors.New("macaroon.verify: seal did not match"))
+	}
+	if vr.seals == nil {
+		vr.seals = make([]int, len(vr.stack))
+		for i := range vr.seals {
+			vr.seals[i] = -1
+		}
+	}
+	vr.seals[vi] = ci
+	return nil
+}
+
+//go:generate go tool -modfile=tools.mod golang.org/x/tools/cmd/stringer -type=PredicateOrigin -linecomment -output seal_string.go
+
+// PredicateOrigin describes who added a predicate to its macaroon, according to the seal of the macaroon.
+type PredicateOrigin int
+
+const (
+	PredicateOriginUnknown = PredicateOrigin(iota) // unknown
+	PredicateIssued                                // issued
+	PredicateDelegated                             // delegated
+)
+
+// origin returns the origin of the caveat at index ci of the macaroon at index si of the stack.
+// It is unknown if the macaroon was not sealed, or the stack was not verified by [Scheme.Verify].
+func (v *VerifiedStack) origin(si int, ci int) PredicateOrigin {
+	if si >= len(v.seals) || v.seals[si] < 0 {
+		return PredicateOriginUnknown
+	}
+	if ci <= v.seals[si] {
+		return PredicateIssued
+	}
+	return PredicateDelegated
+}
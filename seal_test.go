@@ -0,0 +1,145 @@
+package mack_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestScheme_Seal(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	target, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("org = acme"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	if target, err = sch.AddThirdPartyCaveat(&target, testhelpers.ThirdPartyKey, []byte("3p"), "https://auth.example.org"); err != nil {
+		t.Fatalf("AddThirdPartyCaveat: %v", err)
+	}
+	if target, err = sch.Seal(&target, testhelpers.RootKey); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if target, err = sch.AddFirstPartyCaveat(&target, []byte("app = web")); err != nil {
+		t.Fatalf("AddFirstPartyCaveat: %v", err)
+	}
+	discharge, err := sch.NewMacaroon("https://auth.example.org", []byte("3p"), testhelpers.ThirdPartyKey, []byte("user = alice"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	if discharge, err = sch.Seal(&discharge, testhelpers.ThirdPartyKey); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	stack, err := sch.PrepareStack(&target, []mack.Macaroon{discharge})
+	if err != nil {
+		t.Fatalf("PrepareStack: %v", err)
+	}
+	ctx := context.Background()
+	vs, err := sch.Verify(ctx, testhelpers.RootKey, stack)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	expected := []mack.PredicateOrigin{mack.PredicateIssued, mack.PredicateIssued, mack.PredicateDelegated, mack.PredicateIssued, mack.PredicateIssued}
+	checkOrigins := func(t *testing.T, vs *mack.VerifiedStack, expected []mack.PredicateOrigin) {
+		t.Helper()
+		predicates := vs.Predicates()
+		if len(predicates) != len(expected) {
+			t.Fatalf("Predicates: expected %d predicates, got %d", len(expected), len(predicates))
+		}
+		for i := range predicates {
+			if predicates[i].Origin != expected[i] {
+				t.Errorf("Predicates[%d]: %v: want origin %v, got %v", i, predicates[i], expected[i], predicates[i].Origin)
+			}
+		}
+	}
+	checkOrigins(t, &vs, expected)
+	err = vs.Clear(ctx, mack.PredicateCheckerFunc(func(_ context.Context, predicate []byte) (bool, error) {
+		return !bytes.HasPrefix(predicate, []byte(mack.SealPrefix)), nil
+	}))
+	if err != nil {
+		t.Fatalf("Clear: expected seal caveats to be satisfied: %v", err)
+	}
+	checkOrigins(t, mack.InsecureVerifiedStack(stack), []mack.PredicateOrigin{0, 0, 0, 0, 0})
+
+	cache, err := mack.NewVerifyCache(sch, mack.VerifyCacheConfig{TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewVerifyCache: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if vs, err = cache.Verify(ctx, testhelpers.RootKey, stack); err != nil {
+			t.Fatalf("VerifyCache.Verify: %v", err)
+		}
+		checkOrigins(t, &vs, expected)
+	}
+}
+
+func TestScheme_Seal_forged(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("org = acme"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	if m, err = sch.AddFirstPartyCaveat(&m, []byte("app = web")); err != nil {
+		t.Fatalf("AddFirstPartyCaveat: %v", err)
+	}
+	if m, err = sch.Seal(&m, testhelpers.ThirdPartyKey); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	_, err = sch.Verify(context.Background(), testhelpers.RootKey, mack.Stack{m})
+	var ve *mack.VerificationError
+	if !errors.As(err, &ve) || ve.Reason != mack.VerificationSealMismatch || ve.CaveatIndex != 2 {
+		t.Fatalf("Verify: expected a seal mismatch at caveat 2, got %v", err)
+	}
+	if _, err = sch.Seal(&m, []byte("short")); !errors.Is(err, mack.ErrInvalidArgument) {
+		t.Fatalf("Seal: expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestScheme_Seal_tag(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte("target"), testhelpers.RootKey, []byte("org = acme"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	sealed, err := sch.Seal(&m, testhelpers.RootKey)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	caveats := sealed.Caveats()
+	seal := string(caveats[len(caveats)-1].ID())
+	if tag, herr := hex.DecodeString(strings.TrimPrefix(seal, mack.SealPrefix)); herr != nil || len(tag) != sch.KeySize() {
+		t.Fatalf("Seal: expected a hex-encoded tag, got %q", seal)
+	}
+
+	// A holder of a macaroon with the ID "mack-seal" minted with the same key knows HMAC(key, "mack-seal"),
+	// which must not be the seal key.
+	domain, err := sch.UnsafeRootMacaroon("loc", []byte("mack-seal"), testhelpers.RootKey)
+	if err != nil {
+		t.Fatalf("UnsafeRootMacaroon: %v", err)
+	}
+	tag, err := sch.UnsafeRootMacaroon("loc", m.Signature(), domain.Signature())
+	if err != nil {
+		t.Fatalf("UnsafeRootMacaroon: %v", err)
+	}
+	for name, predicate := range map[string]string{
+		"domain":    mack.SealPrefix + hex.EncodeToString(tag.Signature()),
+		"malformed": mack.SealPrefix + "not hex",
+	} {
+		t.Run(name, func(t *testing.T) {
+			forged, ferr := sch.AddFirstPartyCaveat(&m, []byte(predicate))
+			if ferr != nil {
+				t.Fatalf("AddFirstPartyCaveat: %v", ferr)
+			}
+			_, ferr = sch.Verify(context.Background(), testhelpers.RootKey, mack.Stack{forged})
+			var ve *mack.VerificationError
+			if !errors.As(ferr, &ve) || ve.Reason != mack.VerificationSealMismatch {
+				t.Fatalf("Verify: expected a seal mismatch, got %v", ferr)
+			}
+		})
+	}
+}
@@ -45,6 +45,31 @@ func TestSensibleScheme_Validate_allocs(t *testing.T) {
 	t.Logf("AllocsPerRun: %d", int(allocs))
 }
 
+func TestSensibleScheme_Builder_allocs(t *testing.T) {
+	sch := Scheme()
+	m, err := sch.UnsafeRootMacaroon("1p", []byte("hello"), testhelpers.RootKey)
+	if err != nil {
+		t.Fatalf("UnsafeRootMacaroon: %v", err)
+	}
+	b := sch.NewBuilder(&m).
+		FirstParty([]byte(`a > 1`)).
+		FirstParty([]byte(`b > 2`)).
+		FirstParty([]byte(`user = foo`))
+	allocs := testing.AllocsPerRun(1024, func() {
+		if _, err = b.Build(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	// one allocation for the macaroon data, and one to index its caveats.
+	// third-party caveats are not added, since the encryption scheme may allocate.
+	const expected = 2
+	if allocs > expected {
+		writeHeapProfile(t)
+		t.Fatalf("allocs: %d > %d", int(allocs), expected)
+	}
+	t.Logf("AllocsPerRun: %d", int(allocs))
+}
+
 func writeHeapProfile(t *testing.T) {
 	t.Helper()
 	var err error
@@ -79,24 +79,48 @@ func (a *Attenuator) Attenuate(ctx context.Context, m *macaroon.Macaroon, predic
 			err = fmt.Errorf("thirdparty.Attenuate: %w", err)
 		}
 	}()
-	cKey := make([]byte, a.scheme.KeySize())
+	cKey, cID, err := a.issue(ctx, predicate)
+	if err != nil {
+		return am, err
+	}
+	am, err = a.scheme.AddThirdPartyCaveat(m, cKey, cID, a.location)
+	if err != nil {
+		return am, err
+	}
+	return am, nil
+}
+
+// AttenuateBuilder adds a third-party caveat to the builder, like [Attenuator.Attenuate].
+// The caveat is appended to the macaroon by [macaroon.Builder.Build], with any other caveats added to the builder.
+// If the builder already recorded an error, it is returned without issuing a caveat id.
+func (a *Attenuator) AttenuateBuilder(ctx context.Context, b *macaroon.Builder, predicate []byte) error {
+	if err := b.Err(); err != nil {
+		return fmt.Errorf("thirdparty.AttenuateBuilder: %w", err)
+	}
+	cKey, cID, err := a.issue(ctx, predicate)
+	if err != nil {
+		return fmt.Errorf("thirdparty.AttenuateBuilder: %w", err)
+	}
+	b.ThirdParty(cKey, cID, a.location)
+	return nil
+}
+
+// issue generates a new random caveat key, and issues a caveat id for the key and predicate.
+func (a *Attenuator) issue(ctx context.Context, predicate []byte) (cKey []byte, cID []byte, err error) {
+	cKey = make([]byte, a.scheme.KeySize())
 	n, err := a.readFunc(cKey)
 	if err != nil {
-		return am, fmt.Errorf("thirdparty.Attenuate: failed to generate key: %w", err)
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
 	}
 	if n != len(cKey) {
-		return am, fmt.Errorf("thirdparty.Attenuate: not enough bytes returned for key. expected: %d, got: %d", len(cKey), n)
+		return nil, nil, fmt.Errorf("not enough bytes returned for key. expected: %d, got: %d", len(cKey), n)
 	}
-	cID, err := a.issuer.IssueCaveatID(ctx, Ticket{
+	cID, err = a.issuer.IssueCaveatID(ctx, Ticket{
 		CaveatKey: cKey,
 		Predicate: predicate,
 	})
 	if err != nil {
-		return am, err
-	}
-	am, err = a.scheme.AddThirdPartyCaveat(m, cKey, cID, a.location)
-	if err != nil {
-		return am, err
+		return nil, nil, err
 	}
-	return am, nil
+	return cKey, cID, nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 
+	macaroon "github.com/justenwalker/mack"
 	"github.com/justenwalker/mack/internal/testhelpers"
 	"github.com/justenwalker/mack/thirdparty"
 )
@@ -94,3 +95,69 @@ func TestService_Attenuate(t *testing.T) {
 		})
 	}
 }
+
+func TestService_AttenuateBuilder(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	ctx := context.Background()
+	m, err := sch.UnsafeRootMacaroon("1p", []byte(`hello`), testhelpers.RootKey)
+	if err != nil {
+		t.Fatalf("UnsafeRootMacaroon: %v", err)
+	}
+	var tickets []thirdparty.Ticket
+	var mock CaveatIDIssuerMock
+	mock.IssueCaveatIDFunc = func(_ context.Context, ticket thirdparty.Ticket) ([]byte, error) {
+		tickets = append(tickets, ticket)
+		return append([]byte(`cid:`), ticket.Predicate...), nil
+	}
+	svc, err := thirdparty.NewAttenuator(thirdparty.AttenuatorConfig{
+		Location:     "3p",
+		Scheme:       sch,
+		CaveatIssuer: &mock,
+	}, thirdparty.WithRandSource(testhelpers.ReadRandom))
+	if err != nil {
+		t.Fatalf("NewAttenuator: unexpected error: %v", err)
+	}
+	b := sch.NewBuilder(&m).FirstParty([]byte(`a > 1`))
+	for _, predicate := range []string{`user == foo`, `group == bar`} {
+		if err = svc.AttenuateBuilder(ctx, b, []byte(predicate)); err != nil {
+			t.Fatalf("AttenuateBuilder: unexpected error: %v", err)
+		}
+	}
+	am, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	tpcs := am.ThirdPartyCaveats()
+	if len(tpcs) != len(tickets) {
+		t.Fatalf("expected %d third-party caveats, got %d", len(tickets), len(tpcs))
+	}
+	discharges := make([]macaroon.Macaroon, len(tickets))
+	for i, ticket := range tickets {
+		if diff := cmp.Diff(`cid:`+string(ticket.Predicate), string(tpcs[i].ID())); diff != "" {
+			t.Fatalf("caveat.ID: (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff("3p", tpcs[i].Location()); diff != "" {
+			t.Fatalf("caveat.Location: (-want +got):\n%s", diff)
+		}
+		discharges[i], err = sch.UnsafeRootMacaroon("3p", tpcs[i].ID(), ticket.CaveatKey)
+		if err != nil {
+			t.Fatalf("UnsafeRootMacaroon: %v", err)
+		}
+	}
+	stack, err := sch.PrepareStack(&am, discharges)
+	if err != nil {
+		t.Fatalf("PrepareStack: %v", err)
+	}
+	if _, err = sch.Verify(ctx, testhelpers.RootKey, stack); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tickets = nil
+	b = sch.NewBuilder(&m).FirstParty(nil)
+	if err = svc.AttenuateBuilder(ctx, b, []byte(`user == foo`)); !errors.Is(err, macaroon.ErrInvalidArgument) {
+		t.Fatalf("AttenuateBuilder: expected the builder error, got %v", err)
+	}
+	if len(tickets) != 0 {
+		t.Fatalf("AttenuateBuilder: expected no caveat id to be issued, got %d", len(tickets))
+	}
+}
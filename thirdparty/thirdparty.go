@@ -119,3 +119,5 @@ func (tps Set) dischargeCaveat(ctx context.Context, cp *macaroon.Caveat) (macaro
 		err:    ErrNoMatchingThirdParty,
 	}
 }
+
+var _ PredicateChecker = (*macaroon.PredicateMux)(nil)
@@ -0,0 +1,182 @@
+// Package timecaveat provides first-party caveats which bound the validity of a macaroon in time.
+//
+// Three caveats are supported, each consisting of a prefix followed by an RFC3339 timestamp:
+//
+//	time-before 2006-01-02T15:04:05Z   the macaroon expires at the given time
+//	time-after 2006-01-02T15:04:05Z    the macaroon is only valid after the given time
+//	not-before 2006-01-02T15:04:05Z    the macaroon is only valid at, or after the given time
+//
+// Caveats are added with [AddTimeBefore], [AddTimeAfter] and [AddNotBefore], and cleared with a [Checker]
+// which evaluates them against an injectable clock with a configurable skew tolerance.
+// A [Checker] may be registered with a [mack.PredicateMux] to handle only these prefixes.
+//
+// [Expiry] computes the effective expiry of a whole [mack.Stack], which is useful for setting cache and cookie lifetimes.
+package timecaveat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/justenwalker/mack"
+)
+
+// Caveat prefixes.
+const (
+	PrefixTimeBefore = "time-before "
+	PrefixTimeAfter  = "time-after "
+	PrefixNotBefore  = "not-before "
+)
+
+type stringError string
+
+func (e stringError) Error() string {
+	return string(e)
+}
+
+// ErrNotTimeCaveat is returned by [Parse] and [Checker] when the predicate is not a time caveat.
+const ErrNotTimeCaveat = stringError("timecaveat: not a time caveat")
+
+// Kind identifies a time caveat.
+type Kind int
+
+const (
+	KindUnknown = Kind(iota)
+	KindTimeBefore
+	KindTimeAfter
+	KindNotBefore
+)
+
+func (k Kind) prefix() string {
+	switch k {
+	case KindTimeBefore:
+		return PrefixTimeBefore
+	case KindTimeAfter:
+		return PrefixTimeAfter
+	case KindNotBefore:
+		return PrefixNotBefore
+	default:
+		return ""
+	}
+}
+
+// TimeBefore returns a caveat which is satisfied only before t.
+func TimeBefore(t time.Time) []byte {
+	return format(KindTimeBefore, t)
+}
+
+// TimeAfter returns a caveat which is satisfied only after t.
+func TimeAfter(t time.Time) []byte {
+	return format(KindTimeAfter, t)
+}
+
+// NotBefore returns a caveat which is satisfied at, or after t.
+func NotBefore(t time.Time) []byte {
+	return format(KindNotBefore, t)
+}
+
+func format(k Kind, t time.Time) []byte {
+	return t.UTC().AppendFormat([]byte(k.prefix()), time.RFC3339Nano)
+}
+
+// AddTimeBefore appends a time-before caveat to the macaroon, returning a new Macaroon.
+func AddTimeBefore(s *mack.Scheme, m *mack.Macaroon, t time.Time) (mack.Macaroon, error) {
+	return s.AddFirstPartyCaveat(m, TimeBefore(t))
+}
+
+// AddTimeAfter appends a time-after caveat to the macaroon, returning a new Macaroon.
+func AddTimeAfter(s *mack.Scheme, m *mack.Macaroon, t time.Time) (mack.Macaroon, error) {
+	return s.AddFirstPartyCaveat(m, TimeAfter(t))
+}
+
+// AddNotBefore appends a not-before caveat to the macaroon, returning a new Macaroon.
+func AddNotBefore(s *mack.Scheme, m *mack.Macaroon, t time.Time) (mack.Macaroon, error) {
+	return s.AddFirstPartyCaveat(m, NotBefore(t))
+}
+
+// Parse parses a time caveat, returning its Kind and timestamp.
+// If the predicate does not have a time caveat prefix, the error wraps [ErrNotTimeCaveat].
+func Parse(predicate []byte) (Kind, time.Time, error) {
+	for _, k := range []Kind{KindTimeBefore, KindTimeAfter, KindNotBefore} {
+		prefix := k.prefix()
+		if !bytes.HasPrefix(predicate, []byte(prefix)) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, string(predicate[len(prefix):]))
+		if err != nil {
+			return KindUnknown, time.Time{}, fmt.Errorf("timecaveat: could not parse time in caveat '%s': %w", string(predicate), err)
+		}
+		return k, t, nil
+	}
+	return KindUnknown, time.Time{}, ErrNotTimeCaveat
+}
+
+// Checker evaluates time caveats.
+type Checker struct {
+	// Now returns the current time. If nil, [time.Now] is used.
+	Now func() time.Time
+	// Skew is the tolerance allowed for clock differences between the minting and verifying services.
+	// Each bound is relaxed by this amount.
+	Skew time.Duration
+}
+
+// CheckPredicate evaluates the time caveat against the checker's clock.
+// A predicate which is not a time caveat returns an error wrapping [ErrNotTimeCaveat].
+func (c *Checker) CheckPredicate(_ context.Context, predicate []byte) (bool, error) {
+	k, t, err := Parse(predicate)
+	if err != nil {
+		return false, err
+	}
+	now := c.now()
+	switch k {
+	case KindTimeBefore:
+		return now.Before(t.Add(c.Skew)), nil
+	case KindTimeAfter:
+		return now.After(t.Add(-c.Skew)), nil
+	case KindNotBefore:
+		return !now.Before(t.Add(-c.Skew)), nil
+	default:
+		return false, ErrNotTimeCaveat
+	}
+}
+
+// Register registers the checker with the mux for all time caveat prefixes.
+func (c *Checker) Register(mux *mack.PredicateMux) error {
+	for _, prefix := range []string{PrefixTimeBefore, PrefixTimeAfter, PrefixNotBefore} {
+		if err := mux.Handle(prefix, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checker) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// Expiry returns the effective expiry of the stack; the earliest time-before caveat found on any macaroon,
+// including discharge macaroons. It returns false if none of the macaroons expire.
+// Caveats which cannot be parsed are ignored, since they would fail to clear anyway.
+func Expiry(stack mack.Stack) (time.Time, bool) {
+	var exp time.Time
+	var found bool
+	for i := range stack {
+		for _, c := range stack[i].FirstPartyCaveats() {
+			k, t, err := Parse(c.ID())
+			if err != nil || k != KindTimeBefore {
+				continue
+			}
+			if !found || t.Before(exp) {
+				exp = t
+				found = true
+			}
+		}
+	}
+	return exp, found
+}
+
+var _ mack.PredicateChecker = (*Checker)(nil)
@@ -0,0 +1,129 @@
+package timecaveat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+	"github.com/justenwalker/mack/timecaveat"
+)
+
+func TestChecker_CheckPredicate(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		predicate []byte
+		skew      time.Duration
+		expected  bool
+	}{
+		{name: "before-ok", predicate: timecaveat.TimeBefore(now.Add(time.Second)), expected: true},
+		{name: "before-fail", predicate: timecaveat.TimeBefore(now), expected: false},
+		{name: "before-skew", predicate: timecaveat.TimeBefore(now.Add(-time.Second)), skew: time.Minute, expected: true},
+		{name: "after-ok", predicate: timecaveat.TimeAfter(now.Add(-time.Second)), expected: true},
+		{name: "after-fail", predicate: timecaveat.TimeAfter(now), expected: false},
+		{name: "after-skew", predicate: timecaveat.TimeAfter(now.Add(time.Second)), skew: time.Minute, expected: true},
+		{name: "not-before-ok", predicate: timecaveat.NotBefore(now), expected: true},
+		{name: "not-before-fail", predicate: timecaveat.NotBefore(now.Add(time.Second)), expected: false},
+		{name: "not-before-skew", predicate: timecaveat.NotBefore(now.Add(time.Second)), skew: time.Minute, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := timecaveat.Checker{
+				Now:  func() time.Time { return now },
+				Skew: tt.skew,
+			}
+			ok, err := c.CheckPredicate(ctx, tt.predicate)
+			if err != nil {
+				t.Fatalf("CheckPredicate(%s): unexpected error: %v", string(tt.predicate), err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("CheckPredicate(%s): want %v, got %v", string(tt.predicate), tt.expected, ok)
+			}
+		})
+	}
+	t.Run("not-time-caveat", func(t *testing.T) {
+		var c timecaveat.Checker
+		if _, err := c.CheckPredicate(ctx, []byte(`org = acme`)); !errors.Is(err, timecaveat.ErrNotTimeCaveat) {
+			t.Fatalf("expected ErrNotTimeCaveat, got %v", err)
+		}
+	})
+	t.Run("invalid-time", func(t *testing.T) {
+		var c timecaveat.Checker
+		_, err := c.CheckPredicate(ctx, []byte(`time-before tomorrow`))
+		if err == nil || errors.Is(err, timecaveat.ErrNotTimeCaveat) {
+			t.Fatalf("expected parse error, got %v", err)
+		}
+	})
+}
+
+func TestExpiry(t *testing.T) {
+	exp := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: string(timecaveat.TimeBefore(exp.Add(time.Hour)))},
+			{ID: string(timecaveat.NotBefore(exp.Add(-time.Hour)))},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: string(timecaveat.TimeBefore(exp))},
+				},
+			},
+		},
+	})
+	got, ok := timecaveat.Expiry(fx.Stack)
+	if !ok {
+		t.Fatalf("expected stack to expire")
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("Expiry: (-want +got):\n%s", diff)
+	}
+	if _, ok = timecaveat.Expiry(mack.Stack{*fx.Stack.Target()}); !ok {
+		t.Fatalf("expected target to expire")
+	}
+}
+
+func TestChecker_Register(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	sch := testhelpers.NewScheme(t)
+	m, err := sch.NewMacaroon("loc", []byte(`id`), testhelpers.RootKey, []byte(`org = acme`))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	if m, err = timecaveat.AddTimeBefore(sch, &m, now.Add(time.Hour)); err != nil {
+		t.Fatalf("AddTimeBefore: %v", err)
+	}
+	if m, err = timecaveat.AddNotBefore(sch, &m, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("AddNotBefore: %v", err)
+	}
+	if m, err = timecaveat.AddTimeAfter(sch, &m, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("AddTimeAfter: %v", err)
+	}
+	var mux mack.PredicateMux
+	_ = mux.HandleFunc("org = ", func(context.Context, []byte) (bool, error) {
+		return true, nil
+	})
+	c := timecaveat.Checker{Now: func() time.Time { return now }}
+	if err = c.Register(&mux); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	vs, err := sch.Verify(ctx, testhelpers.RootKey, mack.Stack{m})
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err = vs.Clear(ctx, &mux); err != nil {
+		t.Fatalf("Clear: unexpected error: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if err = vs.Clear(ctx, &mux); !errors.Is(err, mack.ErrPredicateNotSatisfied) {
+		t.Fatalf("Clear: expected ErrPredicateNotSatisfied after expiry, got %v", err)
+	}
+}
@@ -26,13 +26,17 @@ type TraceOp struct {
 	Arg2   []byte
 	Result []byte
 	Error  error
+	// Discharge is the index of the trace of the discharge macaroon matched to the third-party caveat
+	// decrypted by a TraceOpDecrypt operation. It is zero if no discharge was matched.
+	Discharge int
 }
 
 type jsonTraceOp struct {
-	Kind   string   `json:"kind,omitempty"`
-	Args   []string `json:"args,omitempty"`
-	Result string   `json:"result,omitempty"`
-	Error  []string `json:"error,omitempty"`
+	Kind      string   `json:"kind,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	Result    string   `json:"result,omitempty"`
+	Discharge int      `json:"discharge,omitempty"`
+	Error     []string `json:"error,omitempty"`
 }
 
 func (op *TraceOp) MarshalJSON() ([]byte, error) {
@@ -50,10 +54,11 @@ func (op *TraceOp) MarshalJSON() ([]byte, error) {
 		args = append(args, printableBytes(op.Arg2))
 	}
 	return json.Marshal(jsonTraceOp{
-		Kind:   op.Kind.String(),
-		Args:   args,
-		Result: printableBytes(op.Result),
-		Error:  traceErrors,
+		Kind:      op.Kind.String(),
+		Args:      args,
+		Result:    printableBytes(op.Result),
+		Discharge: op.Discharge,
+		Error:     traceErrors,
 	})
 }
 
@@ -91,6 +96,8 @@ func GetTraces(ctx context.Context) Traces {
 
 type verifyContext struct {
 	stacks []Trace
+	// redactKey is set by WithRedactedVerifyContext.
+	redactKey []byte
 }
 
 type verifyContextKey struct{}
@@ -120,6 +127,24 @@ func (v *verifyContext) init(stack Stack) {
 	v.stacks = make([]Trace, len(stack))
 }
 
+// finish redacts the traces, if required, once verification is done.
+// The secrets recorded during verification are overwritten.
+func (v *verifyContext) finish() {
+	if v == nil || v.redactKey == nil {
+		return
+	}
+	redacted := Traces(v.stacks).Redact(v.redactKey)
+	for i := range v.stacks {
+		zeroBytes(v.stacks[i].RootKey)
+		for _, op := range v.stacks[i].Ops {
+			zeroBytes(op.Arg1)
+			zeroBytes(op.Arg2)
+			zeroBytes(op.Result)
+		}
+	}
+	v.stacks = redacted
+}
+
 func (v *verifyContext) traceRootKey(index int, key []byte, id []byte) *TraceOp {
 	if v == nil {
 		return nil
@@ -152,6 +177,13 @@ func (op *TraceOp) setResult(r []byte) {
 	op.Result = cloneBytes(r)
 }
 
+func (op *TraceOp) setDischarge(i int) {
+	if op == nil {
+		return
+	}
+	op.Discharge = i
+}
+
 func (op *TraceOp) setError(err error) {
 	if op == nil {
 		return
@@ -168,6 +200,12 @@ func cloneBytes(vs []byte) []byte {
 	return bs
 }
 
+func zeroBytes(bs []byte) {
+	for i := range bs {
+		bs[i] = 0
+	}
+}
+
 func getVerifyContext(ctx context.Context) *verifyContext {
 	if v := ctx.Value(verifyContextKey{}); v != nil {
 		return v.(*verifyContext) //nolint:forcetypeassert
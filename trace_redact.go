@@ -0,0 +1,123 @@
+package mack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// fingerprintSize is the number of bytes of the keyed hash kept in a fingerprint.
+const fingerprintSize = 8
+
+// fingerprintPrefix marks a value in a trace as a fingerprint of redacted secret material.
+const fingerprintPrefix = "fp:"
+
+// WithRedactedVerifyContext creates a new context like [WithVerifyContext], but the traces returned by [GetTraces]
+// are redacted with the given key, as if by [Traces.Redact]. Secret material is redacted when [Scheme.Verify] returns,
+// so the traces retained by the context are safe to log.
+func WithRedactedVerifyContext(ctx context.Context, key []byte) context.Context {
+	return context.WithValue(ctx, verifyContextKey{}, &verifyContext{redactKey: cloneBytes(key)})
+}
+
+// Redact returns a copy of the traces with secret material replaced by short keyed fingerprints.
+// Root keys, caveat keys, and signatures are fingerprinted, as are their hex encodings in error messages.
+// Macaroon and caveat IDs, op kinds, and the order of operations are preserved.
+//
+// A fingerprint is a truncated HMAC-SHA256 of the secret under the given key, so equal secrets have equal fingerprints
+// within traces redacted with the same key. This makes it possible to follow a signature from the result of one
+// operation to the argument of the next, without revealing it. The key should itself be secret and long-lived,
+// to prevent an attacker from testing guesses against a fingerprint.
+func (t Traces) Redact(key []byte) Traces {
+	if t == nil {
+		return nil
+	}
+	r := traceRedactor{key: key, hexes: make(map[string]string)}
+	out := make(Traces, len(t))
+	for i := range t {
+		out[i].RootKey = r.fingerprint(t[i].RootKey)
+		out[i].Ops = make([]*TraceOp, len(t[i].Ops))
+		for j, op := range t[i].Ops {
+			rop := TraceOp{
+				Kind:      op.Kind,
+				Arg1:      r.fingerprint(op.Arg1),
+				Arg2:      cloneBytes(op.Arg2),
+				Result:    r.fingerprint(op.Result),
+				Error:     op.Error,
+				Discharge: op.Discharge,
+			}
+			if op.Kind == TraceOpBind { // both arguments of a bind are signatures.
+				rop.Arg2 = r.fingerprint(op.Arg2)
+			}
+			out[i].Ops[j] = &rop
+		}
+	}
+	// errors are redacted last, so that every secret in the trace is known.
+	for i := range out {
+		for _, op := range out[i].Ops {
+			op.Error = r.redactError(op.Error)
+		}
+	}
+	return out
+}
+
+type traceRedactor struct {
+	key []byte
+	// hexes maps the hex encoding of each fingerprinted secret to its fingerprint.
+	hexes map[string]string
+}
+
+func (r *traceRedactor) fingerprint(secret []byte) []byte {
+	if secret == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write(secret)
+	sum := mac.Sum(nil)
+	fp := fingerprintPrefix + hex.EncodeToString(sum[:fingerprintSize])
+	if len(secret) > 0 {
+		r.hexes[hex.EncodeToString(secret)] = fp
+	}
+	return []byte(fp)
+}
+
+func (r *traceRedactor) redactString(s string) string {
+	for h, fp := range r.hexes {
+		s = strings.ReplaceAll(s, h, fp)
+	}
+	return s
+}
+
+func (r *traceRedactor) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{
+		msg:   r.redactString(err.Error()),
+		next:  r.redactError(errors.Unwrap(err)),
+		cause: err,
+	}
+}
+
+// redactedError replaces the message of each error in a chain with a redacted message.
+// It still matches the original errors with [errors.Is], but does not expose them with [errors.As],
+// since their messages may contain secrets.
+type redactedError struct {
+	msg   string
+	next  error
+	cause error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.next
+}
+
+func (e *redactedError) Is(target error) bool {
+	return errors.Is(e.cause, target)
+}
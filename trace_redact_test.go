@@ -0,0 +1,104 @@
+package mack_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+
+	macaroon "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+var redactKey = []byte("trace-redaction-key")
+
+// helperFailedTraces verifies a stack with a discharge that is not bound to the request using a context
+// from newContext, and returns the traces recorded.
+func helperFailedTraces(t *testing.T, newContext func(ctx context.Context) context.Context) macaroon.Traces {
+	t.Helper()
+	ctx := newContext(context.Background())
+	sch := testhelpers.NewScheme(t)
+	testhelpers.SeedRandom(1000)
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{ID: "b > 2"},
+			{
+				ID:         "{cK,userid == foo}",
+				ThirdParty: "https://other.example.org",
+			},
+			{ID: "user = foo"},
+		},
+	})
+	stack := make([]macaroon.Macaroon, 0, len(fx.Stack))
+	stack = append(stack, *fx.Target)
+	stack = append(stack, fx.Discharge...) // not bound to request
+	if _, err := sch.Verify(ctx, testhelpers.RootKey, stack); err == nil {
+		t.Fatalf("expected verify to fail")
+	}
+	return macaroon.GetTraces(ctx)
+}
+
+func TestTraces_Redact(t *testing.T) {
+	g := goldie.New(t, goldie.WithFixtureDir("testdata/traces"))
+	traces := helperFailedTraces(t, macaroon.WithVerifyContext)
+	redacted := traces.Redact(redactKey)
+	out := redacted.String()
+	t.Log(out)
+	var secrets [][]byte
+	for _, tr := range traces {
+		secrets = append(secrets, tr.RootKey)
+		for _, op := range tr.Ops {
+			secrets = append(secrets, op.Result)
+			if op.Kind != macaroon.TraceOpFail {
+				secrets = append(secrets, op.Arg1)
+			}
+		}
+	}
+	for _, s := range secrets {
+		if len(s) == 0 {
+			continue
+		}
+		if strings.Contains(out, hex.EncodeToString(s)) {
+			t.Fatalf("redacted traces contain secret: %x", s)
+		}
+	}
+	for i := range traces {
+		for j, op := range traces[i].Ops {
+			if op.Kind == macaroon.TraceOpBind {
+				continue
+			}
+			if !bytes.Equal(op.Arg2, redacted[i].Ops[j].Arg2) {
+				t.Fatalf("trace[%d].op[%d]: expected %s to be preserved, got %s", i, j, op.Arg2, redacted[i].Ops[j].Arg2)
+			}
+		}
+	}
+	var failed bool
+	for _, op := range redacted[0].Ops {
+		if op.Kind == macaroon.TraceOpFail {
+			failed = errors.Is(op.Error, macaroon.ErrVerificationFailed)
+		}
+	}
+	if !failed {
+		t.Fatalf("expected redacted failure to match ErrVerificationFailed")
+	}
+	if traces.String() == out {
+		t.Fatalf("expected original traces to be unmodified")
+	}
+	g.Assert(t, "TestTraces_Redact", []byte(out))
+}
+
+func TestWithRedactedVerifyContext(t *testing.T) {
+	expected := helperFailedTraces(t, macaroon.WithVerifyContext).Redact(redactKey)
+	actual := helperFailedTraces(t, func(ctx context.Context) context.Context {
+		return macaroon.WithRedactedVerifyContext(ctx, redactKey)
+	})
+	if expected.String() != actual.String() {
+		t.Fatalf("redacted traces mismatch:\nwant: %s\ngot: %s", expected, actual)
+	}
+}
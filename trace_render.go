@@ -0,0 +1,198 @@
+package mack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// abbreviateSize is the number of bytes of a binary value shown by the trace renderers.
+const abbreviateSize = 8
+
+// WriteTree writes the traces as an indented tree, starting from the target macaroon.
+// Each discharge macaroon is nested under the third-party caveat it was matched to, followed by its operations,
+// ending with the binding of its signature to the request. Discharge macaroons which were not matched are listed last.
+//
+// Binary values are abbreviated; use [Traces.String] for the full values. The root key and decrypted caveat keys
+// are replaced by placeholders, but each intermediate signature is the HMAC key of the next operation,
+// so the output reveals a prefix of those keys and should be handled like the traces themselves.
+func (t Traces) WriteTree(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	tw := treeWriter{w: bw, traces: t, seen: make([]bool, len(t))}
+	if len(t) > 0 {
+		tw.writeTrace(0, "")
+	}
+	for i := 1; i < len(t); i++ {
+		if !tw.seen[i] {
+			_, _ = fmt.Fprintf(bw, "unused ")
+			tw.writeTrace(i, "")
+		}
+	}
+	return bw.Flush()
+}
+
+type treeWriter struct {
+	w      *bufio.Writer
+	traces Traces
+	seen   []bool
+}
+
+func (tw *treeWriter) writeTrace(i int, indent string) {
+	tw.seen[i] = true
+	tr := &tw.traces[i]
+	_, _ = fmt.Fprintf(tw.w, "macaroon[%d]", i)
+	if id := tr.id(); id != nil {
+		_, _ = fmt.Fprintf(tw.w, " %s", abbreviateBytes(id))
+	}
+	_ = tw.w.WriteByte('\n')
+	for j, op := range tr.Ops {
+		branch, next := "├── ", "│   "
+		if j == len(tr.Ops)-1 {
+			branch, next = "└── ", "    "
+		}
+		_, _ = fmt.Fprintf(tw.w, "%s%s%s\n", indent, branch, op.summary(tr.RootKey))
+		if op.Discharge <= 0 || op.Discharge >= len(tw.traces) {
+			continue
+		}
+		_, _ = fmt.Fprintf(tw.w, "%s%s└── ", indent, next)
+		if tw.seen[op.Discharge] {
+			_, _ = fmt.Fprintf(tw.w, "macaroon[%d] (see above)\n", op.Discharge)
+			continue
+		}
+		tw.writeTrace(op.Discharge, indent+next+"    ")
+	}
+}
+
+// WriteDOT writes the traces as a Graphviz DOT graph.
+// Each macaroon is a node, labeled with its ID, with an edge to each discharge macaroon matched to one of its
+// third-party caveats. Macaroons which failed verification are colored red, and are linked to a node describing
+// the failure. Discharge macaroons which were not matched are dashed.
+func (t Traces) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	used := make([]bool, len(t))
+	for i := range t {
+		for _, op := range t[i].Ops {
+			if op.Discharge > 0 && op.Discharge < len(t) {
+				used[op.Discharge] = true
+			}
+		}
+	}
+	_, _ = fmt.Fprintln(bw, "digraph traces {")
+	_, _ = fmt.Fprintln(bw, "  node [shape=box];")
+	for i := range t {
+		tr := &t[i]
+		label := fmt.Sprintf("macaroon[%d]", i)
+		if id := tr.id(); id != nil {
+			label += "\n" + abbreviateBytes(id)
+		}
+		var attrs []string
+		if i > 0 && !used[i] {
+			attrs = append(attrs, "style=dashed")
+		}
+		failure := tr.failure()
+		if failure != nil {
+			attrs = append(attrs, "color=red")
+		}
+		_, _ = fmt.Fprintf(bw, "  m%d [label=%s%s];\n", i, dotQuote(label), dotAttrs(attrs))
+		for j, op := range tr.Ops {
+			if op.Discharge > 0 && op.Discharge < len(t) {
+				_, _ = fmt.Fprintf(bw, "  m%d -> m%d [label=%s];\n", i, op.Discharge, dotQuote(fmt.Sprintf("op %d", j)))
+			}
+		}
+		if failure != nil {
+			_, _ = fmt.Fprintf(bw, "  m%d_fail [label=%s, shape=note, color=red];\n", i, dotQuote(errorChain(failure.Error)))
+			_, _ = fmt.Fprintf(bw, "  m%d -> m%d_fail [color=red];\n", i, i)
+		}
+	}
+	_, _ = fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// id returns the macaroon ID, which is the second argument of the first operation.
+func (t *Trace) id() []byte {
+	if len(t.Ops) == 0 || t.Ops[0].Kind != TraceOpHMAC {
+		return nil
+	}
+	return t.Ops[0].Arg2
+}
+
+// failure returns the first failed operation in the trace, or nil.
+func (t *Trace) failure() *TraceOp {
+	for _, op := range t.Ops {
+		if op.Kind == TraceOpFail && op.Error != nil {
+			return op
+		}
+	}
+	return nil
+}
+
+// summary renders the operation on a single line, replacing the root key of its macaroon and decrypted caveat keys.
+func (op *TraceOp) summary(rootKey []byte) string {
+	if op.Kind == TraceOpFail {
+		if op.Error == nil {
+			return op.Kind.String()
+		}
+		return op.Kind.String() + ": " + errorChain(op.Error)
+	}
+	var sb strings.Builder
+	sb.WriteString(op.Kind.String())
+	sb.WriteByte('(')
+	if op.Kind == TraceOpHMAC && rootKey != nil && bytes.Equal(op.Arg1, rootKey) {
+		sb.WriteString("<root key>")
+	} else {
+		sb.WriteString(abbreviateBytes(op.Arg1))
+	}
+	sb.WriteString(", ")
+	sb.WriteString(abbreviateBytes(op.Arg2))
+	sb.WriteString(") = ")
+	if op.Kind == TraceOpDecrypt {
+		sb.WriteString("<caveat key>")
+	} else {
+		sb.WriteString(abbreviateBytes(op.Result))
+	}
+	if op.Error != nil {
+		sb.WriteString(": ")
+		sb.WriteString(errorChain(op.Error))
+	}
+	return sb.String()
+}
+
+// errorChain renders the message of each error in the chain, like [TraceOp.MarshalJSON],
+// skipping messages which are already included in the message of the error wrapping them.
+func errorChain(err error) string {
+	var msgs []string
+	var prev string
+	for ; err != nil; err = errors.Unwrap(err) {
+		msg := err.Error()
+		if !strings.Contains(prev, msg) {
+			msgs = append(msgs, msg)
+		}
+		prev = msg
+	}
+	return strings.Join(msgs, ": ")
+}
+
+// abbreviateBytes renders printable values in full, and binary values as a hex prefix.
+func abbreviateBytes(bs []byte) string {
+	str := printableBytes(bs)
+	if !strings.HasPrefix(str, "0x") || len(bs) <= abbreviateSize {
+		return str
+	}
+	return "0x" + hex.EncodeToString(bs[:abbreviateSize]) + "…"
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func dotAttrs(attrs []string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(attrs, ", ")
+}
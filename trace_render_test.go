@@ -0,0 +1,79 @@
+package mack_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+
+	macaroon "github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+func TestTraces_render(t *testing.T) {
+	cfg := testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "{cK,userid == foo}",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "b > 2"},
+					{
+						ID:         "{cK,group == bar}",
+						ThirdParty: "https://groups.example.org",
+					},
+				},
+			},
+			{ID: "user = foo"},
+		},
+	}
+	tests := []struct {
+		name    string
+		stack   func(fx testhelpers.Fixture) macaroon.Stack
+		success bool
+	}{
+		{
+			name:    "success",
+			stack:   func(fx testhelpers.Fixture) macaroon.Stack { return fx.Stack },
+			success: true,
+		},
+		{
+			name: "fail",
+			stack: func(fx testhelpers.Fixture) macaroon.Stack {
+				return append(macaroon.Stack{*fx.Target}, fx.Discharge...) // not bound to request
+			},
+		},
+		{
+			name: "unused",
+			stack: func(fx testhelpers.Fixture) macaroon.Stack {
+				return macaroon.Stack{fx.Stack[0], fx.Stack[2]}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := goldie.New(t, goldie.WithFixtureDir("testdata/traces"))
+			testhelpers.SeedRandom(1000)
+			fx := testhelpers.CreateTestFixture(t, cfg)
+			ctx := macaroon.WithVerifyContext(context.Background())
+			_, err := fx.Scheme.Verify(ctx, testhelpers.RootKey, tt.stack(fx))
+			if (err == nil) != tt.success {
+				t.Fatalf("Verify: unexpected result: %v", err)
+			}
+			traces := macaroon.GetTraces(ctx)
+			var tree, dot bytes.Buffer
+			if err = traces.WriteTree(&tree); err != nil {
+				t.Fatalf("WriteTree: %v", err)
+			}
+			t.Log("\n" + tree.String())
+			if err = traces.WriteDOT(&dot); err != nil {
+				t.Fatalf("WriteDOT: %v", err)
+			}
+			g.Assert(t, "TestTraces_WriteTree_"+tt.name, tree.Bytes())
+			g.Assert(t, "TestTraces_WriteDOT_"+tt.name, dot.Bytes())
+		})
+	}
+}
@@ -57,8 +57,11 @@ func TestTraces_fail(t *testing.T) {
 	if diff := cmp.Diff(testhelpers.ThirdPartyKey, traces[1].RootKey); diff != "" {
 		t.Fatalf("trace[0].RootKey mismatch (-want +got):\n%s", diff)
 	}
-	if len(traces[1].Ops) != 2 {
-		t.Fatalf("expected trace[1] to have 2 operations, got %d", len(traces[1].Ops))
+	if len(traces[1].Ops) != 3 {
+		t.Fatalf("expected trace[1] to have 3 operations, got %d", len(traces[1].Ops))
+	}
+	if op := traces[1].Ops[2]; op.Kind != macaroon.TraceOpFail {
+		t.Fatalf("expected the failure of trace[1] to be recorded, got %v", op.Kind)
 	}
 	g.Assert(t, "TestTraces_fail", []byte(traces.String()))
 }
@@ -2,6 +2,7 @@ package mack
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
@@ -22,7 +23,7 @@ func (s Stack) Discharges() []Macaroon {
 
 // InsecureVerifiedStack converts the [Stack] into a [VerifiedStack] without actually verifying it.
 // It may be useful if the verification was already done on the [Stack] before and that result was cached.
-// Generally, it is insecure to use this, instead use the [Scheme.Verify] function.
+// Generally, it is insecure to use this, instead use the [Scheme.Verify] function, or a [VerifyCache] to cache results.
 func InsecureVerifiedStack(stack Stack) *VerifiedStack {
 	return &VerifiedStack{
 		stack: stack,
@@ -33,6 +34,9 @@ func InsecureVerifiedStack(stack Stack) *VerifiedStack {
 type VerifiedStack struct {
 	verified bool
 	stack    Stack
+	// seals contains the index of the last seal caveat of each macaroon, or -1 if it is not sealed.
+	// It is nil if no macaroon in the stack is sealed.
+	seals []int
 }
 
 // ID returns the authorization macaroon ID.
@@ -49,21 +53,27 @@ func (v *VerifiedStack) Verified() bool {
 // Predicates returns the list of first-party predicates found in the verified stack.
 func (v *VerifiedStack) Predicates() []Predicate {
 	var predicates []Predicate
-	for i := range v.stack {
-		predicates = append(predicates, caveatsToPredicates(&v.stack[i])...)
+	for si := range v.stack {
+		predicates = v.appendPredicates(predicates, si)
 	}
 	return predicates
 }
 
-func caveatsToPredicates(m *Macaroon) []Predicate {
-	cs := m.FirstPartyCaveats()
-	predicates := make([]Predicate, len(cs))
-	for i := range cs {
-		predicates[i] = Predicate{
+// appendPredicates appends the first-party predicates of the macaroon at index si of the stack.
+func (v *VerifiedStack) appendPredicates(predicates []Predicate, si int) []Predicate {
+	m := &v.stack[si]
+	var i int
+	for cur := m.data.cursor(); cur.next(); {
+		if cur.caveat.thirdParty() {
+			continue
+		}
+		predicates = append(predicates, Predicate{
 			MacaroonID: m.ID(),
-			CaveatID:   cs[i].ID(),
+			CaveatID:   cur.caveat.ID(),
 			Index:      i,
-		}
+			Origin:     v.origin(si, cur.index),
+		})
+		i++
 	}
 	return predicates
 }
@@ -74,34 +84,155 @@ func caveatsToPredicates(m *Macaroon) []Predicate {
 // The resulting error should also have a Predicate() function, that returns the predicate which failed.
 func (v *VerifiedStack) Clear(ctx context.Context, pcheck PredicateChecker) error {
 	for i := range v.stack {
-		if err := checkMacaroon(ctx, &v.stack[i], pcheck); err != nil {
+		if err := v.checkMacaroon(ctx, i, pcheck); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func checkMacaroon(ctx context.Context, m *Macaroon, pcheck PredicateChecker) error {
-	for i := range m.Caveats() {
-		if m.caveatAt(i).thirdParty() {
+func (v *VerifiedStack) checkMacaroon(ctx context.Context, si int, pcheck PredicateChecker) error {
+	m := &v.stack[si]
+	for cur := m.data.cursor(); cur.next(); {
+		if cur.caveat.thirdParty() {
 			continue
 		}
 		predicate := Predicate{
 			MacaroonID: m.ID(),
-			CaveatID:   m.caveatAt(i).ID(),
-			Index:      i,
+			CaveatID:   cur.caveat.ID(),
+			Index:      cur.index,
+			Origin:     v.origin(si, cur.index),
 		}
-		ok, err := pcheck.CheckPredicate(ctx, predicate.CaveatID)
-		if err != nil {
-			return fmt.Errorf("macaroon.Caveat: failed to verify caveat '%v': %w", &predicate, err)
+		if _, err := checkPredicate(ctx, predicate, pcheck); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPredicate checks a single predicate, returning its outcome and the error which describes it, if not satisfied.
+// Seal caveats are always satisfied, since they do not restrict the macaroon.
+func checkPredicate(ctx context.Context, predicate Predicate, pcheck PredicateChecker) (PredicateOutcome, error) {
+	if isSeal(predicate.CaveatID) {
+		return PredicateSatisfied, nil
+	}
+	ok, err := pcheck.CheckPredicate(ctx, predicate.CaveatID)
+	if err != nil {
+		return PredicateError, fmt.Errorf("macaroon.Caveat: failed to verify caveat '%v': %w", &predicate, err)
+	}
+	if !ok {
+		return PredicateNotSatisfied, &predicateNotSatisfiedError{
+			predicate: predicate,
+		}
+	}
+	return PredicateSatisfied, nil
+}
+
+// ClearAll clears the verified stack like [VerifiedStack.Clear], but instead of stopping at the first failure,
+// it evaluates every first-party predicate across the target and all discharge macaroons.
+// It returns a [ClearReport] containing the outcome of each predicate, in stack order.
+// The returned error is [ClearReport.Err]; it is nil if and only if every predicate was satisfied.
+func (v *VerifiedStack) ClearAll(ctx context.Context, pcheck PredicateChecker) (ClearReport, error) {
+	predicates := v.stackPredicates()
+	report := make(ClearReport, len(predicates))
+	for i := range predicates {
+		outcome, err := checkPredicate(ctx, predicates[i].Predicate, pcheck)
+		report[i] = PredicateResult{
+			Predicate:  predicates[i].Predicate,
+			StackIndex: predicates[i].stackIndex,
+			Outcome:    outcome,
+			Err:        err,
 		}
-		if !ok {
-			return &predicateNotSatisfiedError{
-				predicate: predicate,
+	}
+	return report, report.Err()
+}
+
+type stackPredicate struct {
+	Predicate
+	stackIndex int
+}
+
+// stackPredicates returns every first-party predicate in the stack, in the order they are checked by [VerifiedStack.Clear].
+func (v *VerifiedStack) stackPredicates() []stackPredicate {
+	var predicates []stackPredicate
+	for si := range v.stack {
+		m := &v.stack[si]
+		for cur := m.data.cursor(); cur.next(); {
+			if cur.caveat.thirdParty() {
+				continue
 			}
+			predicates = append(predicates, stackPredicate{
+				Predicate: Predicate{
+					MacaroonID: m.ID(),
+					CaveatID:   cur.caveat.ID(),
+					Index:      cur.index,
+					Origin:     v.origin(si, cur.index),
+				},
+				stackIndex: si,
+			})
 		}
 	}
-	return nil
+	return predicates
+}
+
+//go:generate go tool -modfile=tools.mod golang.org/x/tools/cmd/stringer -type=PredicateOutcome -linecomment -output verify_string.go
+
+// PredicateOutcome is the outcome of checking a single predicate.
+type PredicateOutcome int
+
+const (
+	PredicateSatisfied    = PredicateOutcome(iota) // satisfied
+	PredicateNotSatisfied                          // not satisfied
+	PredicateError                                 // error
+)
+
+// PredicateResult is the outcome of checking a single predicate with [VerifiedStack.ClearAll].
+type PredicateResult struct {
+	// Predicate is the predicate that was checked.
+	Predicate Predicate
+	// StackIndex is the position of the macaroon in the stack containing the predicate. The target is 0.
+	StackIndex int
+	// Outcome of the check.
+	Outcome PredicateOutcome
+	// Err is nil if the predicate was satisfied.
+	// If the predicate was not satisfied, it satisfies errors.Is(err, ErrPredicateNotSatisfied).
+	// Otherwise, it wraps the error returned by the PredicateChecker.
+	Err error
+}
+
+// ClearReport lists the outcome of every predicate checked by [VerifiedStack.ClearAll].
+type ClearReport []PredicateResult
+
+// Satisfied returns true if every predicate in the report was satisfied.
+func (r ClearReport) Satisfied() bool {
+	for i := range r {
+		if r[i].Outcome != PredicateSatisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the results for the predicates which were not satisfied, or could not be checked.
+func (r ClearReport) Failed() []PredicateResult {
+	var failed []PredicateResult
+	for i := range r {
+		if r[i].Outcome != PredicateSatisfied {
+			failed = append(failed, r[i])
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of all failed predicates, or returns nil if every predicate was satisfied.
+func (r ClearReport) Err() error {
+	var errs []error
+	for i := range r {
+		if r[i].Err != nil {
+			errs = append(errs, r[i].Err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // Predicate is a struct that contains the Macaroon ID, Caveat ID, and the position in the macaroon which it was fond.
@@ -109,6 +240,9 @@ type Predicate struct {
 	MacaroonID []byte
 	CaveatID   []byte
 	Index      int
+	// Origin reports whether the predicate was added by the issuer of the macaroon, or delegated by a holder.
+	// It is only known if the macaroon was sealed with [Scheme.Seal].
+	Origin PredicateOrigin
 }
 
 func (p Predicate) String() string {
@@ -122,3 +256,71 @@ type PredicateChecker interface {
 	// but rather, that the predicate cannot be verified at this time.
 	CheckPredicate(ctx context.Context, predicate []byte) (bool, error)
 }
+
+// VerifyOption configures optional checks performed by [Scheme.Verify].
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	revoker Revoker
+	limits  Limits
+}
+
+// newVerifyOptions applies the options. It returns nil if there are none, so that the common case does not allocate.
+func newVerifyOptions(opts []VerifyOption) *verifyOptions {
+	if len(opts) == 0 {
+		return nil
+	}
+	o := &verifyOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// verifier holds the state shared by every macaroon verified during a single call to [Scheme.Verify].
+type verifier struct {
+	ctx      context.Context
+	s        *Scheme
+	stack    Stack
+	trace    *verifyContext
+	observer VerifyObserver
+	maxDepth int
+	maxOps   int
+	ops      int
+	seals    []int
+	// failed is set by the first call to failedAt, which records where verification failed.
+	failed     bool
+	failStack  int
+	failCaveat int
+}
+
+func (vr *verifier) observe(ev VerifyEvent) {
+	if vr.observer != nil {
+		vr.observer.ObserveVerify(vr.ctx, ev)
+	}
+}
+
+// checkContext returns an error if the context of the verification is done.
+func (vr *verifier) checkContext() error {
+	if err := vr.ctx.Err(); err != nil {
+		return fmt.Errorf("macaroon.verify: %w", err)
+	}
+	return nil
+}
+
+// spend counts an HMAC or decrypt operation, returning a [LimitError] if it exceeds MaxOperations.
+func (vr *verifier) spend() error {
+	vr.ops++
+	return checkLimit("MaxOperations", vr.maxOps, vr.ops)
+}
+
+// failedAt records the position of the first failure. Later calls are ignored,
+// so that the innermost macaroon in a discharge chain is reported.
+func (vr *verifier) failedAt(vi int, ci int) {
+	if vr.failed {
+		return
+	}
+	vr.failed = true
+	vr.failStack = vi
+	vr.failCaveat = ci
+}
@@ -0,0 +1,248 @@
+package mack
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+)
+
+// DefaultVerifyCacheSize is the maximum number of results held by a [VerifyCache], if not configured.
+const DefaultVerifyCacheSize = 4096
+
+// VerifyCacheConfig configures a [VerifyCache].
+type VerifyCacheConfig struct {
+	// TTL is how long a successful verification is cached. It must be positive.
+	TTL time.Duration
+
+	// NegativeTTL is how long a failed verification is cached.
+	// If it is zero, failures are never cached. It may not exceed TTL.
+	NegativeTTL time.Duration
+
+	// MaxEntries is the maximum number of results to cache. When it is reached, the least recently used result is evicted.
+	// If it is zero, DefaultVerifyCacheSize is used.
+	MaxEntries int
+
+	// Now returns the current time. If it is nil, time.Now is used.
+	Now func() time.Time
+}
+
+// VerifyCache caches the results of [Scheme.Verify].
+// Results are keyed by a SHA-256 digest of the root key, the [Limits] given with [WithLimits],
+// and the encoding of every macaroon in the stack, so a result is only reused for an identical stack and key.
+//
+// Only the cryptographic verification is cached. A [Revoker] given with [WithRevoker] is consulted on every call,
+// so that a revoked macaroon is rejected immediately, even if its verification was cached.
+// Failures are only cached if they are a [*VerificationError]. Only the reason and location of the failure
+// are cached, and the error is rebuilt for the stack of each caller.
+//
+// A VerifyCache is safe for concurrent use.
+type VerifyCache struct {
+	scheme      *Scheme
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[verifyCacheKey]*list.Element
+	lru     *list.List
+}
+
+type verifyCacheKey [sha256.Size]byte
+
+type verifyCacheEntry struct {
+	key     verifyCacheKey
+	expires time.Time
+	seals   []int
+	failure *verifyCacheFailure
+}
+
+// verifyCacheFailure is a cached [VerificationError]. It does not refer to the stack which failed,
+// so that the cache does not retain it.
+type verifyCacheFailure struct {
+	reason      VerificationReason
+	stackIndex  int
+	caveatIndex int
+	err         error
+}
+
+// error rebuilds the [VerificationError] for the stack, which must be identical to the stack which failed.
+func (f *verifyCacheFailure) error(stack Stack) error {
+	m := &stack[f.stackIndex]
+	if f.caveatIndex >= 0 {
+		for cur := m.data.cursor(); cur.next(); {
+			if cur.index == f.caveatIndex {
+				return caveatVerificationError(f.reason, m, f.stackIndex, f.caveatIndex, cur.caveat.ID(), f.err)
+			}
+		}
+	}
+	return verificationError(f.reason, m, f.stackIndex, f.err)
+}
+
+// NewVerifyCache creates a new [VerifyCache] which verifies stacks using the scheme.
+func NewVerifyCache(s *Scheme, cfg VerifyCacheConfig) (*VerifyCache, error) {
+	if s == nil {
+		return nil, fmt.Errorf("%w: scheme is required", ErrInvalidArgument)
+	}
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("%w: TTL must be positive", ErrInvalidArgument)
+	}
+	if cfg.NegativeTTL < 0 || cfg.NegativeTTL > cfg.TTL {
+		return nil, fmt.Errorf("%w: NegativeTTL must be between 0 and TTL", ErrInvalidArgument)
+	}
+	if cfg.MaxEntries < 0 {
+		return nil, fmt.Errorf("%w: MaxEntries must not be negative", ErrInvalidArgument)
+	}
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = DefaultVerifyCacheSize
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &VerifyCache{
+		scheme:      s,
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		maxEntries:  cfg.MaxEntries,
+		now:         cfg.Now,
+		entries:     make(map[verifyCacheKey]*list.Element),
+		lru:         list.New(),
+	}, nil
+}
+
+// Verify verifies the stack like [Scheme.Verify], returning the cached result if the same stack was recently verified
+// with the same key and limits.
+func (c *VerifyCache) Verify(ctx context.Context, key []byte, stack Stack, opts ...VerifyOption) (VerifiedStack, error) {
+	if len(stack) == 0 {
+		return VerifiedStack{}, fmt.Errorf("%w: empty stack", ErrInvalidArgument)
+	}
+	o := newVerifyOptions(opts)
+	var limits Limits
+	if o != nil {
+		limits = o.limits
+	}
+	ck := verifyCacheDigest(key, limits, stack)
+	seals, ok, failure := c.get(ck)
+	var err error
+	switch {
+	case !ok:
+		var vs VerifiedStack
+		vs, err = c.scheme.Verify(ctx, key, stack, WithLimits(limits))
+		seals = vs.seals
+		c.put(ck, seals, err)
+	case failure != nil:
+		err = failure.error(stack)
+	}
+	if err != nil {
+		return VerifiedStack{}, err
+	}
+	if o != nil && o.revoker != nil {
+		vr := verifier{ctx: ctx, s: c.scheme, stack: stack}
+		if err = vr.checkRevoked(o.revoker); err != nil {
+			return VerifiedStack{}, err
+		}
+	}
+	return VerifiedStack{
+		verified: true,
+		stack:    stack,
+		seals:    seals,
+	}, nil
+}
+
+// Len returns the number of results in the cache, including results which have expired but were not yet evicted.
+func (c *VerifyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes every result from the cache.
+func (c *VerifyCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[verifyCacheKey]*list.Element)
+	c.lru.Init()
+}
+
+// get returns true if a result was cached for the key and has not expired, and the cached seals or failure.
+func (c *VerifyCache) get(k verifyCacheKey) ([]int, bool, *verifyCacheFailure) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*verifyCacheEntry) //nolint:forcetypeassert
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(el)
+	return e.seals, true, e.failure
+}
+
+func (c *VerifyCache) put(k verifyCacheKey, seals []int, err error) {
+	ttl := c.ttl
+	var failure *verifyCacheFailure
+	if err != nil {
+		var ve *VerificationError
+		if c.negativeTTL == 0 || !errors.As(err, &ve) {
+			return
+		}
+		ttl = c.negativeTTL
+		failure = &verifyCacheFailure{reason: ve.Reason, stackIndex: ve.StackIndex, caveatIndex: ve.CaveatIndex, err: ve.err}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &verifyCacheEntry{key: k, expires: c.now().Add(ttl), seals: seals, failure: failure}
+	if el, ok := c.entries[k]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(e)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *VerifyCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*verifyCacheEntry) //nolint:forcetypeassert
+	delete(c.entries, e.key)
+}
+
+// verifyCacheDigest computes the cache key of a stack. Every variable-length field is length-prefixed,
+// so that distinct inputs cannot produce the same encoding.
+func verifyCacheDigest(key []byte, limits Limits, stack Stack) verifyCacheKey {
+	h := sha256.New()
+	writeDigestInt(h, len(key))
+	h.Write(key)
+	for _, n := range []int{limits.MaxStackSize, limits.MaxCaveats, limits.MaxBytes, limits.MaxDepth, limits.MaxOperations} {
+		writeDigestInt(h, n)
+	}
+	writeDigestInt(h, len(stack))
+	for i := range stack {
+		if stack[i].IsZero() {
+			writeDigestInt(h, 0)
+			continue
+		}
+		bs := stack[i].data.bytes()
+		writeDigestInt(h, len(bs))
+		h.Write(bs)
+	}
+	var k verifyCacheKey
+	h.Sum(k[:0])
+	return k
+}
+
+func writeDigestInt(h hash.Hash, n int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(n))
+	h.Write(buf[:])
+}
@@ -0,0 +1,204 @@
+package mack_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justenwalker/mack"
+	"github.com/justenwalker/mack/internal/testhelpers"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func helperVerifyCache(t *testing.T, cfg mack.VerifyCacheConfig) (testhelpers.Fixture, *mack.VerifyCache, *fakeClock, context.Context, *int) {
+	t.Helper()
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "target",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{
+				ID:         "3p",
+				ThirdParty: "https://other.example.org",
+			},
+		},
+	})
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cfg.Now = clock.Now
+	cache, err := mack.NewVerifyCache(fx.Scheme, cfg)
+	if err != nil {
+		t.Fatalf("NewVerifyCache: %v", err)
+	}
+	var verifications int
+	ctx := mack.WithVerifyObserver(context.Background(), mack.VerifyObserverFunc(func(_ context.Context, ev mack.VerifyEvent) {
+		if ev.Kind == mack.VerifyEventStart {
+			verifications++
+		}
+	}))
+	return fx, cache, clock, ctx, &verifications
+}
+
+func TestVerifyCache_Verify(t *testing.T) {
+	fx, cache, clock, ctx, verifications := helperVerifyCache(t, mack.VerifyCacheConfig{TTL: time.Minute})
+	for i := 0; i < 3; i++ {
+		vs, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack)
+		if err != nil {
+			t.Fatalf("Verify: unexpected error: %v", err)
+		}
+		if !vs.Verified() {
+			t.Fatalf("Verify: expected a verified stack")
+		}
+	}
+	if *verifications != 1 {
+		t.Fatalf("expected the stack to be verified once, got %d", *verifications)
+	}
+	if _, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack, mack.WithLimits(mack.Limits{MaxDepth: 1})); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	if *verifications != 2 {
+		t.Fatalf("expected different limits to be verified again, got %d", *verifications)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if _, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	if *verifications != 3 {
+		t.Fatalf("expected an expired result to be verified again, got %d", *verifications)
+	}
+}
+
+func TestVerifyCache_negative(t *testing.T) {
+	tests := []struct {
+		name          string
+		negativeTTL   time.Duration
+		verifications int
+	}{
+		{name: "disabled", negativeTTL: 0, verifications: 2},
+		{name: "enabled", negativeTTL: time.Second, verifications: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx, cache, clock, ctx, verifications := helperVerifyCache(t, mack.VerifyCacheConfig{
+				TTL:         time.Minute,
+				NegativeTTL: tt.negativeTTL,
+			})
+			for i := 0; i < 2; i++ {
+				if _, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack[:1]); !errors.Is(err, mack.ErrVerificationFailed) {
+					t.Fatalf("Verify: expected ErrVerificationFailed, got %v", err)
+				}
+			}
+			if *verifications != tt.verifications {
+				t.Fatalf("expected %d verifications, got %d", tt.verifications, *verifications)
+			}
+			clock.now = clock.now.Add(tt.negativeTTL)
+			if _, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack[:1]); !errors.Is(err, mack.ErrVerificationFailed) {
+				t.Fatalf("Verify: expected ErrVerificationFailed, got %v", err)
+			}
+			if *verifications != tt.verifications+1 {
+				t.Fatalf("expected the failure to expire after the negative TTL")
+			}
+		})
+	}
+}
+
+func TestVerifyCache_negative_callerStack(t *testing.T) {
+	fx, cache, _, ctx, verifications := helperVerifyCache(t, mack.VerifyCacheConfig{
+		TTL:         time.Minute,
+		NegativeTTL: time.Second,
+	})
+	first := mack.Stack{fx.Stack[0]}
+	second := mack.Stack{fx.Stack[0]}
+	_, err := cache.Verify(ctx, testhelpers.RootKey, first)
+	var want *mack.VerificationError
+	if !errors.As(err, &want) {
+		t.Fatalf("Verify: expected a VerificationError, got %v", err)
+	}
+	_, err = cache.Verify(ctx, testhelpers.RootKey, second)
+	var got *mack.VerificationError
+	if !errors.As(err, &got) {
+		t.Fatalf("Verify: expected a VerificationError, got %v", err)
+	}
+	if *verifications != 1 {
+		t.Fatalf("expected the failure to be cached, got %d verifications", *verifications)
+	}
+	if got.Macaroon() != &second[0] {
+		t.Fatalf("expected the cached failure to refer to the stack of the caller")
+	}
+	if got.Reason != want.Reason || got.StackIndex != want.StackIndex || got.CaveatIndex != want.CaveatIndex {
+		t.Fatalf("expected %v at %d/%d, got %v at %d/%d", want.Reason, want.StackIndex, want.CaveatIndex, got.Reason, got.StackIndex, got.CaveatIndex)
+	}
+	if got.CaveatIndex < 0 || !bytes.Equal(got.CaveatID, want.CaveatID) {
+		t.Fatalf("expected caveat ID %q, got %q", want.CaveatID, got.CaveatID)
+	}
+	if got.Error() != want.Error() {
+		t.Fatalf("expected error %q, got %q", want.Error(), got.Error())
+	}
+}
+
+func TestVerifyCache_evict(t *testing.T) {
+	fx, cache, _, ctx, verifications := helperVerifyCache(t, mack.VerifyCacheConfig{
+		TTL:        time.Minute,
+		MaxEntries: 1,
+	})
+	other, err := fx.Scheme.NewMacaroon("loc", []byte("other"), testhelpers.RootKey, []byte("b > 2"))
+	if err != nil {
+		t.Fatalf("NewMacaroon: %v", err)
+	}
+	stacks := []mack.Stack{fx.Stack, {other}, fx.Stack}
+	for _, st := range stacks {
+		if _, err = cache.Verify(ctx, testhelpers.RootKey, st); err != nil {
+			t.Fatalf("Verify: unexpected error: %v", err)
+		}
+	}
+	if *verifications != 3 {
+		t.Fatalf("expected the least recently used result to be evicted, got %d verifications", *verifications)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("expected 1 cached result, got %d", cache.Len())
+	}
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Fatalf("expected 0 cached results, got %d", cache.Len())
+	}
+}
+
+func TestVerifyCache_revoker(t *testing.T) {
+	fx, cache, _, ctx, verifications := helperVerifyCache(t, mack.VerifyCacheConfig{TTL: time.Minute})
+	var revoked bool
+	revoker := mack.WithRevoker(mack.RevokerFunc(func(_ context.Context, m *mack.Macaroon) (bool, error) {
+		return revoked && string(m.ID()) == "3p", nil
+	}))
+	if _, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack, revoker); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	revoked = true
+	if _, err := cache.Verify(ctx, testhelpers.RootKey, fx.Stack, revoker); !errors.Is(err, mack.ErrRevoked) {
+		t.Fatalf("Verify: expected ErrRevoked, got %v", err)
+	}
+	if *verifications != 1 {
+		t.Fatalf("expected the stack to be verified once, got %d", *verifications)
+	}
+}
+
+func TestNewVerifyCache_invalid(t *testing.T) {
+	sch := testhelpers.NewScheme(t)
+	configs := []mack.VerifyCacheConfig{
+		{},
+		{TTL: time.Second, NegativeTTL: time.Minute},
+		{TTL: time.Second, NegativeTTL: -time.Second},
+		{TTL: time.Second, MaxEntries: -1},
+	}
+	for i, cfg := range configs {
+		if _, err := mack.NewVerifyCache(sch, cfg); !errors.Is(err, mack.ErrInvalidArgument) {
+			t.Errorf("config[%d]: expected ErrInvalidArgument, got %v", i, err)
+		}
+	}
+}
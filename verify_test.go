@@ -236,3 +236,79 @@ func TestVerified_Check(t *testing.T) {
 		})
 	}
 }
+
+func TestVerified_ClearAll(t *testing.T) {
+	ctx := context.Background()
+	fx := testhelpers.CreateTestFixture(t, testhelpers.FixtureConfig{
+		ID: "hello",
+		Caveats: []testhelpers.Caveat{
+			{ID: "a > 1"},
+			{ID: "b > 2"},
+			{
+				ID:         "{cK,userid == foo}",
+				ThirdParty: "https://other.example.org",
+				Caveats: []testhelpers.Caveat{
+					{ID: "c > 3"},
+				},
+			},
+			{ID: "user = foo"},
+		},
+	})
+	var m PredicateCheckerMock
+	m.CheckPredicateFunc = func(_ context.Context, p []byte) (bool, error) {
+		switch string(p) {
+		case "a > 1", "user = foo":
+			return true, nil
+		case "b > 2":
+			return false, nil
+		default:
+			return false, fmt.Errorf("unexpected predicate: %s", string(p))
+		}
+	}
+	v := macaroon.InsecureVerifiedStack(fx.Stack)
+	report, err := v.ClearAll(ctx, &m)
+	if !errors.Is(err, macaroon.ErrPredicateNotSatisfied) {
+		t.Fatalf("expected errors.Is(err, macaroon.ErrPredicateNotSatisfied): %v", err)
+	}
+	if len(m.CheckPredicateCalls()) != 4 {
+		t.Fatalf("expected all 4 predicates to be checked, got %d", len(m.CheckPredicateCalls()))
+	}
+	expected := []struct {
+		caveat     string
+		stackIndex int
+		outcome    macaroon.PredicateOutcome
+	}{
+		{"a > 1", 0, macaroon.PredicateSatisfied},
+		{"b > 2", 0, macaroon.PredicateNotSatisfied},
+		{"user = foo", 0, macaroon.PredicateSatisfied},
+		{"c > 3", 1, macaroon.PredicateError},
+	}
+	if len(report) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(report))
+	}
+	for i, e := range expected {
+		r := report[i]
+		if string(r.Predicate.CaveatID) != e.caveat || r.StackIndex != e.stackIndex || r.Outcome != e.outcome {
+			t.Errorf("report[%d]: want (%s, %d, %v), got (%s, %d, %v)", i, e.caveat, e.stackIndex, e.outcome, r.Predicate.CaveatID, r.StackIndex, r.Outcome)
+		}
+		if (r.Err == nil) != (e.outcome == macaroon.PredicateSatisfied) {
+			t.Errorf("report[%d]: unexpected error: %v", i, r.Err)
+		}
+	}
+	if report.Satisfied() {
+		t.Fatalf("expected report to not be satisfied")
+	}
+	if len(report.Failed()) != 2 {
+		t.Fatalf("expected 2 failed predicates, got %d", len(report.Failed()))
+	}
+	m.CheckPredicateFunc = func(context.Context, []byte) (bool, error) {
+		return true, nil
+	}
+	report, err = v.ClearAll(ctx, &m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Satisfied() {
+		t.Fatalf("expected report to be satisfied")
+	}
+}